@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"pay_slip_generator/pkg/mailer"
+	"pay_slip_generator/pkg/model"
+)
+
+// Report statuses.
+const (
+	statusSent    = "sent"
+	statusFailed  = "failed"  // gave up after retrying transient failures
+	statusBounced = "bounced" // permanent failure, e.g. unknown mailbox; not retried
+	statusSkipped = "skipped"
+	statusError   = "error"
+)
+
+// delivery is one employee's way through the run, from computed pay to the
+// delivered payslip.
+type delivery struct {
+	emp       model.Employee
+	pdfPath   string
+	pdfHash   string
+	key       string // journal key
+	payslipID int64  // ledger id, 0 without a ledger
+	msg       *mailer.Message
+
+	status string
+	detail string
+	err    error // send error
+
+	attempts []mailer.Attempt
+}
+
+// openMailers sets up connections mailers from cfg sharing limiter. It only
+// fails when none can be set up.
+func openMailers(cfg mailer.Config, connections int, limiter *mailer.Limiter) ([]mailer.Mailer, error) {
+	mailers := make([]mailer.Mailer, 0, connections)
+	for range max(connections, 1) {
+		m, err := mailer.New(cfg)
+		if err != nil {
+			if len(mailers) == 0 {
+				return nil, err
+			}
+			break // send over the connections we have
+		}
+		mailers = append(mailers, mailer.WithLimit(m, limiter))
+	}
+	return mailers, nil
+}
+
+// sendAll sends the payslip of every delivery over mailers, retrying
+// transient failures per policy, and closes them. Send failures are recorded
+// on the deliveries. done is called for each delivery as soon as it is sent
+// or given up on, one at a time, so it can journal the send before the next
+// one completes.
+func sendAll(mailers []mailer.Mailer, policy mailer.RetryPolicy, deliveries []*delivery, done func(*delivery)) {
+	jobs := make(chan *delivery)
+	results := make(chan *delivery)
+	var wg sync.WaitGroup
+	for _, m := range mailers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer m.Close()
+			for d := range jobs {
+				d.attempts, d.err = policy.Send(m, d.msg)
+				switch {
+				case d.err == nil:
+					d.status = statusSent
+				case mailer.Classify(d.err) == mailer.Permanent:
+					d.status, d.detail = statusBounced, d.err.Error()
+				default:
+					d.status, d.detail = statusFailed, d.err.Error()
+				}
+				results <- d
+			}
+		}()
+	}
+	go func() {
+		for _, d := range deliveries {
+			jobs <- d
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+	for d := range results {
+		done(d)
+	}
+}
+
+// printReport prints one line per employee in input order.
+func printReport(deliveries []*delivery) {
+	counts := make(map[string]int)
+	fmt.Println("Run report:")
+	for _, d := range deliveries {
+		counts[d.status]++
+		line := fmt.Sprintf("  [%s] %s (%s)", d.status, d.emp.Name, d.emp.Email)
+		if d.detail != "" {
+			line += ": " + d.detail
+		}
+		if len(d.attempts) > 1 {
+			line += fmt.Sprintf(" (%d attempts)", len(d.attempts))
+		}
+		fmt.Println(line)
+		if d.status == statusBounced || len(d.attempts) > 1 {
+			for i, a := range d.attempts {
+				fmt.Printf("      attempt %d: %s\n", i+1, a)
+			}
+		}
+	}
+	fmt.Printf("%d sent, %d failed, %d bounced, %d skipped, %d errors.\n",
+		counts[statusSent], counts[statusFailed], counts[statusBounced], counts[statusSkipped], counts[statusError])
+}
+
+// writeReport writes the report as CSV, in input order.
+func writeReport(path string, deliveries []*delivery) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"name", "email", "month", "year", "status", "detail", "attempts", "attempt_log", "pdf"})
+	for _, d := range deliveries {
+		tries := make([]string, len(d.attempts))
+		for i, a := range d.attempts {
+			tries[i] = a.String()
+		}
+		w.Write([]string{d.emp.Name, d.emp.Email, d.emp.Month, d.emp.Year, d.status, d.detail,
+			strconv.Itoa(len(d.attempts)), strings.Join(tries, "; "), d.pdfPath})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"pay_slip_generator/pkg/journal"
+	"pay_slip_generator/pkg/mailer"
+	"pay_slip_generator/pkg/model"
+)
+
+// stallingMailer sends the first n messages, then blocks until released,
+// like a run killed in the middle of a send.
+type stallingMailer struct {
+	n       int
+	stalled chan struct{}
+	release chan struct{}
+}
+
+func (m *stallingMailer) Send(msg *mailer.Message) error {
+	if m.n == 0 {
+		select {
+		case <-m.stalled:
+		default:
+			close(m.stalled)
+		}
+		<-m.release
+		return fmt.Errorf("interrupted")
+	}
+	m.n--
+	return nil
+}
+
+func (m *stallingMailer) Close() error { return nil }
+
+func TestSendAllJournalsEachSend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	jrnl, err := journal.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jrnl.Close()
+
+	var deliveries []*delivery
+	for i := range 5 {
+		emp := model.Employee{Name: fmt.Sprintf("Emp %d", i), Email: fmt.Sprintf("e%d@a.example", i), Month: "May", Year: "2024"}
+		deliveries = append(deliveries, &delivery{emp: emp, key: fmt.Sprintf("key%d", i), msg: &mailer.Message{To: emp.Email}})
+	}
+	m := &stallingMailer{n: 2, stalled: make(chan struct{}), release: make(chan struct{})}
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		sendAll([]mailer.Mailer{m}, mailer.RetryPolicy{MaxAttempts: 1}, deliveries, func(d *delivery) {
+			journalDelivery(jrnl, d.key, &d.emp, d.pdfHash, d.err)
+		})
+	}()
+
+	// The run is stuck on the third payslip: what a rerun would see now is
+	// what it would see had the process been killed.
+	<-m.stalled
+	rerun, err := journal.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{true, true, false, false, false} {
+		if got := rerun.Delivered(deliveries[i].key); got != want {
+			t.Errorf("mid-run: Delivered(%s) = %v, want %v", deliveries[i].key, got, want)
+		}
+	}
+	rerun.Close()
+
+	close(m.release)
+	<-finished
+	for i, d := range deliveries {
+		if want := i < 2; jrnl.Delivered(d.key) != want {
+			t.Errorf("after run: Delivered(%s) = %v, want %v", d.key, !want, want)
+		}
+	}
+}
+
+func TestResend(t *testing.T) {
+	jrnl, err := journal.Open(filepath.Join(t.TempDir(), "journal.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jrnl.Close()
+	asha := &model.Employee{Name: "Asha Rao", Email: "Asha@a.example", Month: "May", Year: "2024"}
+	ravi := &model.Employee{Name: "Ravi Kumar", Email: "ravi@a.example", Month: "May", Year: "2024"}
+	for _, emp := range []*model.Employee{asha, ravi} {
+		journalDelivery(jrnl, journal.Key(emp, "h"), emp, "h", nil)
+	}
+
+	tests := []struct {
+		resend     string
+		asha, ravi bool // already delivered, so skipped
+	}{
+		{"", true, true},
+		{"asha@A.example", false, true},
+		{" ravi kumar , nobody", true, false},
+		{"Asha Rao,ravi@a.example", false, false},
+	}
+	for _, tt := range tests {
+		resend := resendSet(tt.resend)
+		if got := alreadyDelivered(jrnl, journal.Key(asha, "h"), asha, resend); got != tt.asha {
+			t.Errorf("-resend %q: Asha skipped = %v, want %v", tt.resend, got, tt.asha)
+		}
+		if got := alreadyDelivered(jrnl, journal.Key(ravi, "h"), ravi, resend); got != tt.ravi {
+			t.Errorf("-resend %q: Ravi skipped = %v, want %v", tt.resend, got, tt.ravi)
+		}
+	}
+	// A corrected payslip has a new hash and is sent without -resend.
+	if alreadyDelivered(jrnl, journal.Key(asha, "h2"), asha, resendSet("")) {
+		t.Error("corrected payslip skipped")
+	}
+}
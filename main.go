@@ -1,60 +1,98 @@
 package main
 
 import (
-	"crypto/tls"
 	"flag"
 	"fmt"
+	htmltemplate "html/template"
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
+	"strings"
+	"time"
 
 	"pay_slip_generator/pkg/generator"
+	"pay_slip_generator/pkg/journal"
+	"pay_slip_generator/pkg/ledger"
+	"pay_slip_generator/pkg/mailer"
+	"pay_slip_generator/pkg/mailtemplate"
 	"pay_slip_generator/pkg/model"
 	"pay_slip_generator/pkg/reader"
+	"pay_slip_generator/pkg/run"
 
 	"github.com/joho/godotenv"
-	"gopkg.in/gomail.v2"
 )
 
 func main() {
-	// 0. Parse Flags
-	inputFlag := flag.String("input", "", "Path to input file (CSV or Excel)")
+	// 0. Parse Flags. "preview [flags] <name or email>" renders one
+	// employee's email to stdout instead of running.
+	args := os.Args[1:]
+	preview := len(args) > 0 && args[0] == "preview"
+	if preview {
+		args = args[1:]
+	}
+	inputFlag := flag.String("input", "", fmt.Sprintf("Path to input file (%s)", strings.Join(reader.Formats(), ", ")))
 	dryRunFlag := flag.Bool("dry-run", false, "Run without sending emails")
-	flag.Parse()
+	journalFlag := flag.String("journal", filepath.Join("output", "delivery_journal.jsonl"), "Delivery journal; payslips it records as delivered are not sent again")
+	transportFlag := flag.String("transport", "", "Mail transport: smtp, eml, mbox, maildir or http (default: MAIL_TRANSPORT, else smtp)")
+	connectionsFlag := flag.Int("connections", 1, "Number of parallel mail connections (smtp and http transports)")
+	rateFlag := flag.Int("rate", 0, "Maximum messages sent per minute across all connections (0 = unlimited)")
+	retriesFlag := flag.Int("retries", mailer.DefaultRetry().MaxAttempts, "Maximum send attempts per payslip; transient failures are retried with exponential backoff")
+	retryDelayFlag := flag.Duration("retry-delay", mailer.DefaultRetry().BaseDelay, "Delay before the first retry, doubled for each one after")
+	resendFlag := flag.String("resend", "", "Comma-separated names or emails to send to even if the journal says delivered")
+	templatesFlag := flag.String("templates", "", "Directory with subject.txt, body.txt and body.html email templates (missing files use the built-in ones)")
+	logoFlag := flag.String("logo", "", "Image embedded inline in the HTML email, referenced by templates as {{.Logo}}")
+	cfg := run.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage:\n  %s [flags]\n  %s preview [flags] <name or email>\n\nFlags:\n", os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.CommandLine.Parse(args)
+	if preview && flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
 
 	// 1. Load Configuration
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, relying on environment variables")
 	}
 
-	smtpHost := os.Getenv("SMTP_HOST")
-	smtpPortStr := os.Getenv("SMTP_PORT")
+	mailCfg, err := mailer.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *transportFlag != "" {
+		mailCfg.Transport = *transportFlag
+	}
 	senderEmail := os.Getenv("SMTP_EMAIL")
-	senderPassword := os.Getenv("SMTP_PASSWORD")
+	if senderEmail == "" {
+		senderEmail = os.Getenv("MAIL_FROM")
+	}
 	fromName := os.Getenv("SMTP_FROM_NAME")
 	if fromName == "" {
 		fromName = "HR Team"
 	}
-
-	// In production, don't silently default to Gmail — force correct config.
-	if !*dryRunFlag {
-		if smtpHost == "" || smtpPortStr == "" || senderEmail == "" || senderPassword == "" {
-			log.Fatal("SMTP_HOST, SMTP_PORT, SMTP_EMAIL, SMTP_PASSWORD must be set in .env")
-		}
+	templates, err := mailtemplate.Load(*templatesFlag)
+	if err != nil {
+		log.Fatalf("Error loading email templates: %v", err)
 	}
-
-	smtpPort := 587
-	if smtpPortStr != "" {
-		p, err := strconv.Atoi(smtpPortStr)
-		if err != nil {
-			log.Fatalf("Invalid SMTP_PORT=%q: %v", smtpPortStr, err)
+	if *logoFlag != "" {
+		if _, err := os.Stat(*logoFlag); err != nil {
+			log.Fatalf("Email logo: %v", err)
 		}
-		smtpPort = p
 	}
+	session, err := cfg.Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer session.Close()
+	genOpts := session.Generator
+
+	mailRun := mailtemplate.Run{FromName: fromName, From: senderEmail, Operator: cfg.Operator, Date: time.Now()}
 
 	// 2. Setup Directories
-	inputFile := "employee_payslip_data_10_employees"
+	inputFile := "employee_payslip_data_10_employees.xlsx"
 	if *inputFlag != "" {
 		inputFile = *inputFlag
 	} else if _, err := os.Stat("employees.csv"); err == nil {
@@ -68,109 +106,208 @@ func main() {
 
 	// 3. Read Employees
 	fmt.Printf("Reading employees from %s...\n", inputFile)
-
-	var employees []model.Employee
-	var err error
-
-	if filepath.Ext(inputFile) == ".csv" {
-		employees, err = reader.ReadEmployeesFromCSV(inputFile)
-		if err != nil {
-			log.Fatalf("Error reading CSV: %v", err)
-		}
-	} else {
-		employees, err = reader.ReadEmployees(inputFile)
-		if err != nil {
-			log.Fatalf("Error reading Excel: %v", err)
-		}
+	employees, issues, err := reader.Read(inputFile, session.Reader)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", inputFile, err)
+	}
+	if err := session.CheckIssues(issues, filepath.Join(outputDir, "validation_report")); err != nil {
+		log.Fatal(err)
 	}
 	fmt.Printf("Found %d employees.\n", len(employees))
+	if !*dryRunFlag && !preview {
+		if err := session.Start(inputFile, employees); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	// 4. Setup SMTP Connection (Persistent)
-	var d *gomail.Dialer
-	var s gomail.SendCloser
-
-	if !*dryRunFlag {
-		d = gomail.NewDialer(smtpHost, smtpPort, senderEmail, senderPassword)
+	jrnl, err := journal.Open(*journalFlag)
+	if err != nil {
+		log.Fatalf("Error opening delivery journal: %v", err)
+	}
+	defer jrnl.Close()
+	resend := resendSet(*resendFlag)
 
-		// GoDaddy SMTP commonly uses implicit SSL on 465
-		if smtpPort == 465 {
-			d.SSL = true
+	// 4. Compute pay, in input order
+	deliveries := make([]*delivery, len(employees))
+	var computed []model.Employee
+	var generated []*delivery
+	for i, emp := range employees {
+		d := &delivery{emp: emp}
+		deliveries[i] = d
+		if err := session.Compute(&d.emp); err != nil {
+			log.Printf("  [ERROR] Failed to compute pay for %s: %v\n", emp.Name, err)
+			d.status, d.detail = statusError, "compute: "+err.Error()
+			continue
 		}
+		computed = append(computed, d.emp)
+		generated = append(generated, d)
+	}
 
-		// Helps TLS handshake on many servers
-		d.TLSConfig = &tls.Config{
-			ServerName: smtpHost,
-		}
+	if preview {
+		previewEmail(flag.Arg(0), generated, outputDir, templates, mailRun, *logoFlag, genOpts)
+		return
+	}
 
-		s, err = d.Dial() // IMPORTANT: "=" not ":="
+	// 5. Generate PDFs in parallel
+	fmt.Printf("Generating %d payslips with %d workers...\n", len(computed), cfg.Workers)
+	var pending []*delivery
+	for i, err := range generator.GenerateAll(computed, outputDir, cfg.Workers, genOpts) {
+		d := generated[i]
 		if err != nil {
-			log.Fatalf("Failed to connect to SMTP server %s:%d: %v", smtpHost, smtpPort, err)
+			log.Printf("  [ERROR] Failed to generate PDF for %s: %v\n", d.emp.Name, err)
+			d.status, d.detail = statusError, "generate: "+err.Error()
+			continue
 		}
-		defer s.Close()
-	} else {
-		fmt.Println("[DRY RUN] Skipping SMTP connection.")
-	}
+		d.pdfPath = generator.OutputPath(d.emp, outputDir)
+		d.payslipID = session.Record(&d.emp, d.pdfPath)
 
-	// 5. Process Each Employee
-	for _, emp := range employees {
-		// --- Business Logic ---
-		if emp.LOPDays == "" {
-			emp.LOPDays = "0"
+		if d.emp.Email == "" {
+			d.status, d.detail = statusSkipped, "no email address"
+			session.RecordDelivery(d.payslipID, ledger.Skipped, "", d.detail)
+			continue
 		}
-
-		emp.TotalDeductions = emp.ProfessionalTax + emp.PF
-		emp.NetPay = emp.GrossEarnings - emp.TotalDeductions
-		// ----------------------
-
-		// A. Generate PDF
-		fmt.Printf("Processing %s (%s)...\n", emp.Name, emp.Email)
-		err := generator.GeneratePaySlip(emp, outputDir)
+		d.pdfHash, err = ledger.FileHash(d.pdfPath)
 		if err != nil {
-			log.Printf("  [ERROR] Failed to generate PDF for %s: %v\n", emp.Name, err)
+			d.status, d.detail = statusError, err.Error()
 			continue
 		}
-
-		pdfPath := filepath.Join(outputDir, fmt.Sprintf("%s_%s_%s.pdf", emp.Name, emp.Month, emp.Year))
-
-		// B. Send Email
-		if emp.Email == "" {
-			log.Printf("  [SKIP] No email address for %s\n", emp.Name)
+		d.key = journal.Key(&d.emp, d.pdfHash)
+		if alreadyDelivered(jrnl, d.key, &d.emp, resend) {
+			d.status, d.detail = statusSkipped, "already delivered"
+			session.RecordDelivery(d.payslipID, ledger.Skipped, d.emp.Email, d.detail)
+			continue
+		}
+		d.msg, err = newMessage(&d.emp, d.pdfPath, templates, mailRun, *logoFlag, genOpts)
+		if err != nil {
+			d.status, d.detail = statusError, "email: "+err.Error()
 			continue
 		}
 		if *dryRunFlag {
-			fmt.Printf("  [DRY RUN] Email sending skipped for %s (%s)\n", emp.Name, emp.Email)
+			d.status, d.detail = statusSkipped, "dry run"
 			continue
 		}
+		pending = append(pending, d)
+	}
 
-		m := gomail.NewMessage()
-		m.SetAddressHeader("From", senderEmail, fromName)
-		m.SetHeader("To", emp.Email)
-		m.SetHeader("Subject", fmt.Sprintf("Payslip for %s %s", emp.Month, emp.Year))
-		m.SetBody("text/plain",
-			fmt.Sprintf("Dear %s,\n\nPlease find attached your payslip for %s %s.\n\nBest Regards,\n%s",
-				emp.Name, emp.Month, emp.Year, fromName),
-		)
-		m.Attach(pdfPath)
-
-		// Send using the persistent connection
-		if err := gomail.Send(s, m); err != nil {
-			log.Printf("  [ERROR] Failed to send email to %s: %v\n", emp.Email, err)
-
-			// Simple retry logic: Re-dial if connection dropped
-			_ = s.Close()
-			if s, err = d.Dial(); err == nil {
-				if errRetry := gomail.Send(s, m); errRetry != nil {
-					log.Printf("  [ERROR] Retry failed for %s: %v\n", emp.Email, errRetry)
-				} else {
-					fmt.Printf("  [SUCCESS] Email sent to %s (Retry)\n", emp.Email)
+	// 6. Send through a pool of connections
+	if len(pending) > 0 {
+		connections := *connectionsFlag
+		if mailCfg.Transport != mailer.SMTP && mailCfg.Transport != mailer.HTTP {
+			connections = 1 // file transports write to one place
+		}
+		fmt.Printf("Sending %d payslips over %d %s connection(s)...\n", len(pending), connections, mailCfg.Transport)
+		policy := mailer.DefaultRetry()
+		policy.MaxAttempts, policy.BaseDelay = max(*retriesFlag, 1), *retryDelayFlag
+		mailers, err := openMailers(mailCfg, min(connections, len(pending)), mailer.NewLimiter(*rateFlag))
+		if err != nil {
+			log.Fatalf("Failed to set up %s mail transport: %v", mailCfg.Transport, err)
+		}
+		sendAll(mailers, policy, pending, func(d *delivery) {
+			for _, a := range d.attempts {
+				status, detail := ledger.Sent, ""
+				if a.Err != nil {
+					status, detail = ledger.Failed, a.Err.Error()
 				}
-			} else {
-				log.Printf("  [ERROR] Reconnect failed: %v\n", err)
+				session.RecordDelivery(d.payslipID, status, d.emp.Email, detail)
 			}
-		} else {
-			fmt.Printf("  [SUCCESS] Email sent to %s\n", emp.Email)
-		}
+			journalDelivery(jrnl, d.key, &d.emp, d.pdfHash, d.err)
+		})
+	}
+
+	// 7. Report, in input order
+	printReport(deliveries)
+	if err := writeReport(filepath.Join(outputDir, "run_report.csv"), deliveries); err != nil {
+		log.Printf("Failed to write run report: %v", err)
+	}
+
+	if *dryRunFlag {
+		fmt.Println("[DRY RUN] Pay history and ledger not updated.")
+	} else {
+		session.Finish()
 	}
 
 	fmt.Println("All tasks completed.")
 }
+
+// newMessage renders the payslip email for emp, attaching pdfPath and, if
+// logo is set, embedding it inline. The email is signed for emp's company
+// and, for encrypted payslips, explains how the password is made up.
+func newMessage(emp *model.Employee, pdfPath string, templates *mailtemplate.Templates, run mailtemplate.Run, logo string, genOpts generator.Options) (*mailer.Message, error) {
+	profile, err := genOpts.Companies.For(emp.Entity)
+	if err != nil {
+		return nil, err
+	}
+	data := mailtemplate.Data{Employee: emp, Run: run, Company: profile}
+	if genOpts.Password != nil {
+		data.PasswordHint = genOpts.Password.Describe()
+	}
+	msg := &mailer.Message{
+		FromName:    run.FromName,
+		From:        run.From,
+		To:          emp.Email,
+		Attachments: []string{pdfPath},
+	}
+	if logo != "" {
+		data.Logo = htmltemplate.URL(mailer.ContentID(logo))
+		msg.Inline = []string{logo}
+	}
+	r, err := templates.Render(data)
+	if err != nil {
+		return nil, err
+	}
+	msg.Subject, msg.Text, msg.HTML = r.Subject, r.Text, r.HTML
+	return msg, nil
+}
+
+// previewEmail prints the email of the employee whose name or email is who
+// to stdout.
+func previewEmail(who string, generated []*delivery, outputDir string, templates *mailtemplate.Templates, run mailtemplate.Run, logo string, genOpts generator.Options) {
+	who = strings.ToLower(strings.TrimSpace(who))
+	for _, d := range generated {
+		if strings.ToLower(d.emp.Name) != who && strings.ToLower(d.emp.Email) != who {
+			continue
+		}
+		msg, err := newMessage(&d.emp, generator.OutputPath(d.emp, outputDir), templates, run, logo, genOpts)
+		if err != nil {
+			log.Fatalf("Error rendering email for %s: %v", d.emp.Name, err)
+		}
+		fmt.Printf("From: %s <%s>\nTo: %s\nSubject: %s\n", msg.FromName, msg.From, msg.To, msg.Subject)
+		for _, path := range msg.Inline {
+			fmt.Printf("Inline: %s (%s)\n", path, mailer.ContentID(path))
+		}
+		for _, path := range msg.Attachments {
+			fmt.Printf("Attachment: %s\n", path)
+		}
+		fmt.Printf("\n--- text/plain ---\n%s\n--- text/html ---\n%s\n", msg.Text, msg.HTML)
+		return
+	}
+	log.Fatalf("No employee named %q with computed pay", who)
+}
+
+// journalDelivery appends a delivery attempt to the journal.
+func journalDelivery(jrnl *journal.Journal, key string, emp *model.Employee, pdfHash string, sendErr error) {
+	e := journal.Entry{Key: key, Employee: emp.Name, Email: emp.Email, Month: emp.Month, Year: emp.Year, PDFHash: pdfHash, Status: journal.Delivered}
+	if sendErr != nil {
+		e.Status, e.Error = journal.Failed, sendErr.Error()
+	}
+	if err := jrnl.Record(e); err != nil {
+		log.Printf("  [WARN] Failed to journal delivery to %s: %v", emp.Email, err)
+	}
+}
+
+// resendSet parses the -resend list into lower-case names and emails.
+func resendSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			set[item] = true
+		}
+	}
+	return set
+}
+
+// alreadyDelivered reports whether the journal has key as delivered and emp
+// is not named in the -resend set.
+func alreadyDelivered(jrnl *journal.Journal, key string, emp *model.Employee, resend map[string]bool) bool {
+	return jrnl.Delivered(key) && !resend[strings.ToLower(emp.Email)] && !resend[strings.ToLower(emp.Name)]
+}
@@ -1,18 +1,26 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"pay_slip_generator/pkg/generator"
+	"pay_slip_generator/pkg/model"
 	"pay_slip_generator/pkg/reader"
+	"pay_slip_generator/pkg/run"
 )
 
 func main() {
+	inputFlag := flag.String("input", "employee_payslip_data_10_employees.xlsx", "Path to input file")
+	cfg := run.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
 	fmt.Println("Pay Slip Generator started...")
 
 	// Configuration
-	inputFile := "employee_payslip_data_10_employees.xlsx" // Updated filename
+	inputFile := *inputFlag
 	outputDir := "output"
 
 	// Check if input file exists
@@ -30,25 +38,50 @@ func main() {
 		log.Fatalf("Could not create output directory: %v", err)
 	}
 
+	session, err := cfg.Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer session.Close()
+
 	// 1. Read Employees
 	fmt.Printf("Reading data from %s...\n", inputFile)
-	employees, err := reader.ReadEmployees(inputFile)
+	employees, issues, err := reader.Read(inputFile, session.Reader)
 	if err != nil {
 		// If file not found or invalid, log and exit
-		log.Printf("Error reading Excel file: %v. \n(Note: If file is missing, paste 'employees.xlsx' in the folder)", err)
+		log.Printf("Error reading input file: %v. \n(Note: If file is missing, paste 'employees.xlsx' in the folder)", err)
 		return
 	}
+	if err := session.CheckIssues(issues, filepath.Join(outputDir, "validation_report")); err != nil {
+		log.Fatal(err)
+	}
 	fmt.Printf("Found %d employee records.\n", len(employees))
+	if err := session.Start(inputFile, employees); err != nil {
+		log.Fatal(err)
+	}
 
-	// 2. Generate PDFs
+	// 2. Compute pay, in input order
+	var computed []model.Employee
 	for _, emp := range employees {
-		err := generator.GeneratePaySlip(emp, outputDir)
+		if err := session.Compute(&emp); err != nil {
+			log.Printf("Failed to compute pay for %s: %v", emp.Name, err)
+			continue
+		}
+		computed = append(computed, emp)
+	}
+
+	// 3. Generate PDFs in parallel
+	for i, err := range generator.GenerateAll(computed, outputDir, cfg.Workers, session.Generator) {
+		emp := &computed[i]
 		if err != nil {
 			log.Printf("Failed to generate PDF for %s: %v", emp.Name, err)
-		} else {
-			fmt.Printf("Generated: %s_%s_%s.pdf\n", emp.Name, emp.Month, emp.Year)
+			continue
 		}
+		pdfPath := generator.OutputPath(*emp, outputDir)
+		fmt.Printf("Generated: %s\n", filepath.Base(pdfPath))
+		session.Record(emp, pdfPath)
 	}
+	session.Finish()
 
 	fmt.Println("Processing complete. Check 'output' directory.")
 }
@@ -0,0 +1,214 @@
+// Package company holds the letterhead printed on payslips: the legal
+// entity's name, address, CIN, logo, brand colours and footer. A run can
+// cover several entities, picked per employee by the Entity column.
+package company
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed logo.png
+var defaultLogo []byte
+
+// Profile is one legal entity's letterhead.
+type Profile struct {
+	ID      string   `yaml:"id"` // matched against the employee's Entity column
+	Name    string   `yaml:"name"`
+	Address []string `yaml:"address"` // printed one line each
+	CIN     string   `yaml:"cin"`     // Corporate Identification Number
+	Footer  string   `yaml:"footer"`
+	Colors  Colors   `yaml:"colors"`
+
+	// Logo is an image file, relative to the profile file; LogoBase64
+	// embeds the image in the profile instead. Without either the payslip
+	// has no logo.
+	Logo       string `yaml:"logo"`
+	LogoBase64 string `yaml:"logo_base64"`
+
+	// LogoData and LogoType ("png", "jpg" or "gif") are the loaded logo.
+	LogoData []byte `yaml:"-"`
+	LogoType string `yaml:"-"`
+}
+
+// Colors are the brand colours of a profile.
+type Colors struct {
+	Primary Color `yaml:"primary"` // company name and title bar text
+	Fill    Color `yaml:"fill"`    // shaded bars and table headers
+}
+
+// Color is an RGB colour, written "#RRGGBB" in profile files.
+type Color struct {
+	R, G, B int
+	set     bool
+}
+
+// UnmarshalText parses "#RRGGBB".
+func (c *Color) UnmarshalText(text []byte) error {
+	s := strings.TrimPrefix(strings.TrimSpace(string(text)), "#")
+	v, err := strconv.ParseUint(s, 16, 32)
+	if len(s) != 6 || err != nil {
+		return fmt.Errorf("colour %q: want #RRGGBB", text)
+	}
+	*c = Color{R: int(v >> 16), G: int(v >> 8 & 0xff), B: int(v & 0xff), set: true}
+	return nil
+}
+
+// Or returns c, or def when c was not set.
+func (c Color) Or(def Color) Color {
+	if c.set {
+		return c
+	}
+	return def
+}
+
+// Default colours: black text on light grey bars.
+var (
+	DefaultPrimary = Color{0, 0, 0, true}
+	DefaultFill    = Color{230, 230, 230, true}
+)
+
+// DefaultFooter is printed when a profile has no footer.
+const DefaultFooter = "** This is computer generated payslip and doesn't require signature and stamp"
+
+// Default returns the built-in profile, with its logo compiled into the
+// binary so it does not depend on the working directory.
+func Default() *Profile {
+	return &Profile{
+		Name:     "AbegaTech Pvt. Ltd.",
+		Address:  []string{"P No 147, Floor 1 Rd No7, Sri Madhavam,", "Madeenaguda, Miyapur, Hyderabad 500049"},
+		Footer:   DefaultFooter,
+		LogoData: defaultLogo,
+		LogoType: "png",
+	}
+}
+
+// Profiles are the entities of a profile file, e.g.
+//
+//	default: abega
+//	entities:
+//	  - id: abega
+//	    name: AbegaTech Pvt. Ltd.
+//	    address: ["P No 147, Floor 1 Rd No7, Sri Madhavam,", "Miyapur, Hyderabad 500049"]
+//	    cin: U72200TG2015PTC012345
+//	    logo: logo.png
+//	    colors: {primary: "#1F3A93", fill: "#E8EEF9"}
+//	    footer: "** This is computer generated payslip"
+//
+// Employees without an entity get the default one (the first listed, if
+// default is not set).
+type Profiles struct {
+	byID map[string]*Profile
+	def  *Profile
+}
+
+// Load reads a YAML or JSON profile file and loads every logo it names, so
+// that a missing logo is reported up front rather than per payslip.
+func Load(path string) (*Profiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Default  string     `yaml:"default"`
+		Entities []*Profile `yaml:"entities"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse company profiles %s: %w", path, err)
+	}
+	if len(file.Entities) == 0 {
+		return nil, fmt.Errorf("company profiles %s: no entities", path)
+	}
+
+	ps := &Profiles{byID: make(map[string]*Profile)}
+	for _, p := range file.Entities {
+		if p.Name == "" {
+			return nil, fmt.Errorf("company profiles %s: entity %q has no name", path, p.ID)
+		}
+		key := strings.ToLower(strings.TrimSpace(p.ID))
+		if _, dup := ps.byID[key]; dup {
+			return nil, fmt.Errorf("company profiles %s: entity %q listed twice", path, p.ID)
+		}
+		if err := p.loadLogo(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("company profiles %s: entity %q: %w", path, p.ID, err)
+		}
+		if p.Footer == "" {
+			p.Footer = DefaultFooter
+		}
+		ps.byID[key] = p
+	}
+	ps.def = file.Entities[0]
+	if file.Default != "" {
+		if ps.def = ps.byID[strings.ToLower(file.Default)]; ps.def == nil {
+			return nil, fmt.Errorf("company profiles %s: default entity %q is not listed", path, file.Default)
+		}
+	}
+	return ps, nil
+}
+
+func (p *Profile) loadLogo(dir string) error {
+	switch {
+	case p.Logo != "" && p.LogoBase64 != "":
+		return fmt.Errorf("set logo or logo_base64, not both")
+	case p.LogoBase64 != "":
+		data, err := base64.StdEncoding.DecodeString(p.LogoBase64)
+		if err != nil {
+			return fmt.Errorf("logo_base64: %w", err)
+		}
+		p.LogoData = data
+	case p.Logo != "":
+		path := p.Logo
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("logo not found: %w", err)
+		}
+		p.LogoData = data
+	default:
+		return nil
+	}
+	p.LogoType = imageType(p.LogoData)
+	if p.LogoType == "" {
+		return fmt.Errorf("logo is not a PNG, JPEG or GIF image")
+	}
+	return nil
+}
+
+// imageType sniffs the image format from its first bytes.
+func imageType(data []byte) string {
+	switch {
+	case strings.HasPrefix(string(data), "\x89PNG"):
+		return "png"
+	case strings.HasPrefix(string(data), "\xff\xd8"):
+		return "jpg"
+	case strings.HasPrefix(string(data), "GIF8"):
+		return "gif"
+	}
+	return ""
+}
+
+// For returns the profile of the named entity, or the default one for an
+// empty name. Without profiles (nil) every employee gets Default().
+func (ps *Profiles) For(entity string) (*Profile, error) {
+	if ps == nil {
+		return Default(), nil
+	}
+	entity = strings.TrimSpace(entity)
+	if entity == "" {
+		return ps.def, nil
+	}
+	p, ok := ps.byID[strings.ToLower(entity)]
+	if !ok {
+		return nil, fmt.Errorf("unknown company entity %q", entity)
+	}
+	return p, nil
+}
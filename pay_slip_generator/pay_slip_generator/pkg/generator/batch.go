@@ -0,0 +1,49 @@
+package generator
+
+import (
+	"fmt"
+	"pay_slip_generator/pkg/model"
+	"runtime"
+	"sync"
+)
+
+// GenerateAll generates the payslips of emps with up to workers running at
+// once (the number of CPUs when workers < 1). The returned errors are in the
+// order of emps, nil for every payslip that was written. An employee whose
+// payslip would go to the same file as an earlier one's (the same person
+// twice in the input) is not generated, so that no two emails attach the
+// same file.
+func GenerateAll(emps []model.Employee, outputDir string, workers int, opts Options) []error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	errs := make([]error, len(emps))
+	owner := make(map[string]int, len(emps))
+	var todo []int
+	for i := range emps {
+		path := OutputPath(emps[i], outputDir)
+		if j, ok := owner[path]; ok {
+			errs[i] = fmt.Errorf("%s would overwrite the payslip of %s (%s) listed earlier", path, emps[j].Name, emps[j].Email)
+			continue
+		}
+		owner[path] = i
+		todo = append(todo, i)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(todo)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = GeneratePaySlip(emps[i], outputDir, opts)
+			}
+		}()
+	}
+	for _, i := range todo {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
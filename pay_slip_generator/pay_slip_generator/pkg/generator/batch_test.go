@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+)
+
+func TestOutputPath(t *testing.T) {
+	a := model.Employee{Name: "Ravi Kumar", Email: "ravi@a.example", Month: "Dec", Year: "2024"}
+	b := a
+	b.Email = "ravi.k@a.example"
+	if OutputPath(a, "out") == OutputPath(b, "out") {
+		t.Errorf("namesakes share %s", OutputPath(a, "out"))
+	}
+	if got := filepath.Base(OutputPath(a, "out")); !strings.HasPrefix(got, "Ravi Kumar_Dec_2024_") {
+		t.Errorf("OutputPath = %s, want Ravi Kumar_Dec_2024_<digest>.pdf", got)
+	}
+
+	hi := model.Employee{Name: "रवि क्षत्रिय", Month: "Dec", Year: "2024"}
+	if got := filepath.Base(OutputPath(hi, "out")); !strings.HasPrefix(got, "रवि क्षत्रिय_Dec_2024_") {
+		t.Errorf("OutputPath = %s, want the name with its vowel signs", got)
+	}
+
+	evil := model.Employee{Name: "../../etc/x", Month: "Dec", Year: "2024"}
+	if dir := filepath.Dir(OutputPath(evil, "out")); dir != "out" {
+		t.Errorf("OutputPath(%q) is in %s, want out", evil.Name, dir)
+	}
+}
+
+func TestGenerateAllRejectsSharedFile(t *testing.T) {
+	dir := t.TempDir()
+	emp := model.Employee{Name: "Ravi Kumar", Email: "ravi@a.example", Month: "Dec", Year: "2024", NetPay: money.Rupees(1000)}
+	namesake := emp
+	namesake.Email = "ravi.k@a.example"
+	errs := GenerateAll([]model.Employee{emp, namesake, emp}, dir, 2, Options{})
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("errs = %v, want the first two generated", errs)
+	}
+	if errs[2] == nil {
+		t.Error("duplicate employee: want error")
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("wrote %d files, want 2", len(files))
+	}
+}
+
+func TestHRAAnnexureLayouts(t *testing.T) {
+	emp := model.Employee{Name: "Asha", Email: "asha@a.example", Month: "May", Year: "2024", NetPay: money.Rupees(1000)}
+	for _, m := range []string{"2024-04-01", "2024-05-01"} {
+		month, _ := time.Parse("2006-01-02", m)
+		emp.HRAExemptionMonths = append(emp.HRAExemptionMonths, model.HRAExemptionMonth{
+			Month: month, HRA: money.Rupees(10000), Rent: money.Rupees(15000),
+			RentLessBasic: money.Rupees(13000), BasicShare: money.Rupees(10000), Metro: true, Exempt: money.Rupees(10000),
+		})
+	}
+	for _, name := range LayoutNames() {
+		layout, err := LoadLayout(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := GeneratePaySlip(emp, t.TempDir(), Options{Layout: layout}); err != nil {
+			t.Errorf("layout %s: %v", name, err)
+		}
+	}
+}
+
+func TestUnprintableNames(t *testing.T) {
+	fonts, err := LoadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	emp := model.Employee{Name: "Ravi 中 Kumar", Designation: "Engineer ₹", Month: "May", Year: "2024", NetPay: money.Rupees(1000)}
+	if err := GeneratePaySlip(emp, t.TempDir(), Options{Fonts: fonts}); err != nil {
+		t.Errorf("GeneratePaySlip: %v, want characters without a font printed as ?", err)
+	}
+}
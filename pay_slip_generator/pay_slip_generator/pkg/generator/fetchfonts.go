@@ -0,0 +1,50 @@
+//go:build ignore
+
+// Fetchfonts downloads the Noto Sans fonts payslips are built with that are
+// missing from fonts/. Run it with go generate.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const notoFonts = "https://raw.githubusercontent.com/notofonts/notofonts.github.io/main/fonts"
+
+var families = []string{"NotoSans", "NotoSansDevanagari", "NotoSansTelugu", "NotoSansTamil"}
+
+func main() {
+	for _, family := range families {
+		for _, style := range []string{"Regular", "Bold"} {
+			name := family + "-" + style + ".ttf"
+			if err := fetch(fmt.Sprintf("%s/%s/hinted/ttf/%s", notoFonts, family, name), name); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+}
+
+func fetch(url, name string) error {
+	path := filepath.Join("fonts", name)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", url, err)
+	}
+	log.Printf("fonts/%s (%d bytes)", name, len(data))
+	return os.WriteFile(path, data, 0o644)
+}
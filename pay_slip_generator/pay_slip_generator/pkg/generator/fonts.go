@@ -0,0 +1,228 @@
+package generator
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-text/typesetting/font"
+)
+
+//go:generate go run fetchfonts.go
+
+//go:embed fonts
+var builtinFonts embed.FS
+
+// FontSet is the TrueType fonts payslips are printed with: a base font for
+// Latin text and "₹", and one font per other script. Text is split into
+// runs by script so each run is drawn with a font that has its glyphs.
+// Runs in other scripts are shaped (conjuncts, vowel signs, reordering) and
+// drawn as glyph outlines, since gofpdf only places glyphs one per
+// character. Without a base font payslips use the layout's core font.
+type FontSet struct {
+	base    *fontFamily
+	scripts []*fontFamily // sorted by script name
+}
+
+type fontFamily struct {
+	name    string // gofpdf family name
+	script  string // unicode.Scripts key, "" for the base font
+	table   *unicode.RangeTable
+	regular []byte
+	bold    []byte
+
+	// Parsed fonts of a script family, regular and bold, for shaping.
+	fonts [2]*font.Font
+
+	// No Bold file: bold text is the Regular outlines, stroked as well as
+	// filled.
+	fakeBold bool
+}
+
+// shaped reports whether fam's text is shaped and drawn as outlines rather
+// than printed as PDF text.
+func (fam *fontFamily) shaped() bool {
+	return fam.script != ""
+}
+
+// has reports whether fam has a glyph for r.
+func (fam *fontFamily) has(r rune) bool {
+	if !fam.shaped() {
+		return true // gofpdf prints what it can
+	}
+	_, ok := fam.fonts[0].NominalGlyph(r)
+	return ok
+}
+
+var fontFile = regexp.MustCompile(`^NotoSans([A-Za-z]*)-(Regular|Bold)\.ttf$`)
+
+// LoadFonts loads the built-in fonts (see fonts/README.md) and then those in
+// dir, if not empty, which override built-in files of the same name.
+func LoadFonts(dir string) (*FontSet, error) {
+	files := make(map[string][]byte)
+	if err := readFonts(builtinFonts, "fonts", files); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := readFonts(os.DirFS(dir), ".", files); err != nil {
+			return nil, fmt.Errorf("fonts %s: %w", dir, err)
+		}
+	}
+
+	families := make(map[string]*fontFamily)
+	for name, data := range files {
+		m := fontFile.FindStringSubmatch(name)
+		script := m[1]
+		fam := families[script]
+		if fam == nil {
+			fam = &fontFamily{name: "NotoSans" + script, script: script}
+			if script != "" {
+				if fam.table = unicode.Scripts[script]; fam.table == nil {
+					return nil, fmt.Errorf("font %s: %q is not a Unicode script name", name, script)
+				}
+			}
+			families[script] = fam
+		}
+		if m[2] == "Bold" {
+			fam.bold = data
+		} else {
+			fam.regular = data
+		}
+	}
+
+	set := &FontSet{}
+	for script, fam := range families {
+		if fam.regular == nil {
+			return nil, fmt.Errorf("font %s has Bold but no Regular file", fam.name)
+		}
+		if fam.bold == nil {
+			fam.bold, fam.fakeBold = fam.regular, true
+		}
+		if script == "" {
+			set.base = fam
+			continue
+		}
+		for i, data := range [][]byte{fam.regular, fam.bold} {
+			face, err := font.ParseTTF(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("font %s: %w", fam.name, err)
+			}
+			fam.fonts[i] = face.Font
+		}
+		set.scripts = append(set.scripts, fam)
+	}
+	sort.Slice(set.scripts, func(i, j int) bool { return set.scripts[i].script < set.scripts[j].script })
+	return set, nil
+}
+
+func readFonts(fsys fs.FS, dir string, files map[string][]byte) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !fontFile.MatchString(e.Name()) {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		files[e.Name()] = data
+	}
+	return nil
+}
+
+// Unicode reports whether payslips are printed with TrueType fonts rather
+// than the core font.
+func (set *FontSet) Unicode() bool {
+	return set != nil && set.base != nil
+}
+
+// Scripts lists the scripts other than Latin that have a font.
+func (set *FontSet) Scripts() []string {
+	if set == nil {
+		return nil
+	}
+	var names []string
+	for _, fam := range set.scripts {
+		names = append(names, fam.script)
+	}
+	return names
+}
+
+// familyFor returns the font for r: the font of its script, else the base
+// font for Latin and script-neutral characters. It returns nil when no font
+// has r's script.
+func (set *FontSet) familyFor(r rune) *fontFamily {
+	for _, fam := range set.scripts {
+		if unicode.Is(fam.table, r) {
+			return fam
+		}
+	}
+	if r < 0x80 || unicode.In(r, unicode.Latin, unicode.Common, unicode.Inherited, unicode.Greek, unicode.Cyrillic) {
+		return set.base
+	}
+	return nil
+}
+
+// textRun is a piece of text drawn with one font.
+type textRun struct {
+	family *fontFamily
+	text   string
+}
+
+// runs splits text into runs by font. Spaces, digits and punctuation stay
+// with the run they are in when its font has them, so "Rao / राव" is two
+// runs, not four.
+func (set *FontSet) runs(text string) ([]textRun, error) {
+	var runs []textRun
+	var b strings.Builder
+	var cur *fontFamily
+	for _, r := range text {
+		fam := set.familyFor(r)
+		if fam == nil {
+			return nil, fmt.Errorf("no font for %q (%s script); add NotoSans%s-Regular.ttf to the fonts", text, scriptOf(r), scriptOf(r))
+		}
+		neutral := fam == set.base && unicode.In(r, unicode.Common, unicode.Inherited) && (cur == nil || cur.has(r))
+		if cur == nil {
+			cur = fam
+		} else if fam != cur && !neutral {
+			runs = append(runs, textRun{cur, b.String()})
+			b.Reset()
+			cur = fam
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() > 0 {
+		runs = append(runs, textRun{cur, b.String()})
+	}
+	return runs, nil
+}
+
+// printable replaces the characters of text that no font has with "?".
+func (set *FontSet) printable(text string) string {
+	return strings.Map(func(r rune) rune {
+		if set.familyFor(r) == nil {
+			return '?'
+		}
+		return r
+	}, text)
+}
+
+// scriptOf names r's Unicode script.
+func scriptOf(r rune) string {
+	for name, table := range unicode.Scripts {
+		if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+			return name
+		}
+	}
+	return "Unknown"
+}
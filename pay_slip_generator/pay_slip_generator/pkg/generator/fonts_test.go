@@ -0,0 +1,198 @@
+package generator
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+)
+
+func builtinFontSet(t *testing.T) *FontSet {
+	t.Helper()
+	fonts, err := LoadFonts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fonts.Unicode() {
+		t.Fatal("no built-in NotoSans-Regular.ttf")
+	}
+	return fonts
+}
+
+func TestBuiltinFonts(t *testing.T) {
+	fonts := builtinFontSet(t)
+	if got, want := fonts.Scripts(), []string{"Devanagari", "Tamil"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Scripts() = %v, want %v", got, want)
+	}
+}
+
+func TestRuns(t *testing.T) {
+	fonts := builtinFontSet(t)
+	tests := []struct {
+		text string
+		want []string // family: text
+	}{
+		{"Ravi Kumar", []string{"NotoSans: Ravi Kumar"}},
+		{"Net ₹ 1,000", []string{"NotoSans: Net ₹ 1,000"}},
+		{"Rao / राव", []string{"NotoSans: Rao / ", "NotoSansDevanagari: राव"}},
+		{"राव ₹ 1,000", []string{"NotoSansDevanagari: राव ₹ 1,000"}},
+		{"Kumar குமார்", []string{"NotoSans: Kumar ", "NotoSansTamil: குமார்"}},
+	}
+	for _, tt := range tests {
+		runs, err := fonts.runs(tt.text)
+		if err != nil {
+			t.Errorf("runs(%q): %v", tt.text, err)
+			continue
+		}
+		var got []string
+		for _, run := range runs {
+			got = append(got, run.family.name+": "+run.text)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("runs(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if _, err := fonts.runs("Li 李"); err == nil {
+		t.Error(`runs("Li 李"): want error for a script without a font`)
+	}
+	if got := fonts.printable("Li 李"); got != "Li ?" {
+		t.Errorf("printable = %q, want %q", got, "Li ?")
+	}
+}
+
+func TestShape(t *testing.T) {
+	fonts := builtinFontSet(t)
+	deva := fonts.familyFor('क')
+	var s shaper
+	tests := []struct {
+		text   string
+		glyphs int
+	}{
+		{"क्ष", 1}, // conjunct ligature
+		{"कि", 2},  // vowel sign drawn before the consonant
+		{"रवि", 3},
+	}
+	for _, tt := range tests {
+		out, _ := s.shape(deva, false, tt.text)
+		if len(out.Glyphs) != tt.glyphs {
+			t.Errorf("shape(%q): %d glyphs, want %d", tt.text, len(out.Glyphs), tt.glyphs)
+		}
+		for _, g := range out.Glyphs {
+			if g.GlyphID == 0 {
+				t.Errorf("shape(%q): missing glyph", tt.text)
+			}
+		}
+	}
+	// The i sign comes first although it is typed after the consonant.
+	out, _ := s.shape(deva, false, "कि")
+	if out.Glyphs[0].ClusterIndex != 0 || out.Glyphs[0].GlyphID == out.Glyphs[1].GlyphID {
+		t.Errorf("shape(कि) = %+v", out.Glyphs)
+	}
+}
+
+// renderCell draws text in one cell with the built-in fonts and returns the
+// uncompressed page content.
+func renderCell(t *testing.T, style, text string) string {
+	t.Helper()
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	r := &renderer{pdf: pdf, layout: DefaultLayout(), fonts: builtinFontSet(t), registered: make(map[string]bool)}
+	r.font(style, 10, 10)
+	r.cell(80, 6, text, "", 1, "L", false)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+var (
+	fillOp  = regexp.MustCompile(`(?m)^f$`)
+	glyphOp = regexp.MustCompile(`(?m)^[fB]$`) // filled, or filled and stroked for bold
+)
+
+func TestCellShapedText(t *testing.T) {
+	// The rupee sign stays text in NotoSans: UTF-16BE 20 B9.
+	latin := renderCell(t, "", "Net ₹ 100")
+	if !bytes.Contains([]byte(latin), []byte{0x20, 0xB9}) {
+		t.Error("₹ not printed as text")
+	}
+	if fillOp.MatchString(latin) {
+		t.Error("Latin text drawn as outlines")
+	}
+
+	// Devanagari is drawn as filled outlines, one path per glyph.
+	hindi := renderCell(t, "", "रवि क्षत्रिय")
+	if n := len(fillOp.FindAllString(hindi, -1)); n < 5 {
+		t.Errorf("Devanagari name: %d filled glyphs, want at least 5", n)
+	}
+	// Bold Devanagari, without a Bold font, is filled and stroked.
+	if bold := renderCell(t, "B", "रवि"); fillOp.MatchString(bold) || !glyphOp.MatchString(bold) {
+		t.Error("bold Devanagari not stroked")
+	}
+}
+
+// pageContent returns the decompressed content streams of a generated PDF.
+func pageContent(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	for _, m := range regexp.MustCompile(`(?s)stream\r?\n(.*?)endstream`).FindAllSubmatch(data, -1) {
+		zr, err := zlib.NewReader(bytes.NewReader(m[1]))
+		if err != nil {
+			continue // not deflated, e.g. a font
+		}
+		io.Copy(&out, zr)
+	}
+	return out.String()
+}
+
+func TestBilingualPayslip(t *testing.T) {
+	fonts := builtinFontSet(t)
+	tr, err := LoadTranslations(filepath.Join("..", "..", "translations.hi.example.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.CheckFonts(fonts); err != nil {
+		t.Fatalf("example translations: %v", err)
+	}
+
+	emp := model.Employee{Name: "रवि क्षत्रिय", Email: "ravi@a.example", Month: "May", Year: "2024",
+		BasicPayAmount: money.Rupees(50000), GrossEarnings: money.Rupees(50000), NetPay: money.Rupees(50000)}
+	filled := make(map[bool]int)
+	for _, bilingual := range []bool{false, true} {
+		opts := Options{Fonts: fonts}
+		if bilingual {
+			opts.Translations = tr
+		}
+		dir := t.TempDir()
+		if err := GeneratePaySlip(emp, dir, opts); err != nil {
+			t.Fatal(err)
+		}
+		content := pageContent(t, OutputPath(emp, dir))
+		if !bytes.Contains([]byte(content), []byte{0x20, 0xB9}) {
+			t.Errorf("bilingual=%v: no ₹ before net pay", bilingual)
+		}
+		filled[bilingual] = len(glyphOp.FindAllString(content, -1))
+	}
+	if filled[false] == 0 {
+		t.Error("Devanagari name not drawn")
+	}
+	if filled[true] <= filled[false] {
+		t.Errorf("bilingual payslip draws %d Devanagari glyphs, English only %d: labels not translated", filled[true], filled[false])
+	}
+}
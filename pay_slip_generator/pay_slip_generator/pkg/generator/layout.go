@@ -0,0 +1,314 @@
+package generator
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed layouts
+var builtinLayouts embed.FS
+
+// Layout describes a payslip page as a list of sections drawn top to
+// bottom. It is loaded from YAML or JSON; see layouts/default.yaml for the
+// standard payslip. Lengths are in millimetres and font sizes in points.
+//
+// Text in sections may bind employee data with {Field} placeholders naming
+// any model.Employee field or method without arguments, e.g. {Name}, {DOJ},
+// {NetPayInWords} or {YTD.Gross}.
+type Layout struct {
+	Name     string    `yaml:"name"`
+	Font     string    `yaml:"font"`  // default Arial
+	Left     float64   `yaml:"left"`  // left edge of the content, default 10
+	Width    float64   `yaml:"width"` // content width, default 190
+	Sections []Section `yaml:"sections"`
+}
+
+// Section types.
+const (
+	SectionHeader        = "header"        // logo and company letterhead
+	SectionText          = "text"          // one line of text, e.g. a shaded title bar
+	SectionGrid          = "grid"          // boxed grid of label/value cells
+	SectionPayTable      = "pay_table"     // earnings and deductions with totals
+	SectionNetPay        = "net_pay"       // net pay in figures and words
+	SectionContributions = "contributions" // employer contributions, when any
+	SectionSpace         = "space"         // vertical gap
+	SectionFooter        = "footer"        // company footer text
+	SectionHRAAnnexure   = "hra_annexure"  // HRA exemption page, when computed
+)
+
+// Section is one part of a Layout. Which fields apply depends on Type.
+type Section struct {
+	Type string `yaml:"type"`
+
+	// Font and box of text, text-like rows and footers.
+	Size   float64 `yaml:"size"`
+	Bold   bool    `yaml:"bold"`
+	Color  string  `yaml:"color"` // "primary" for the company colour
+	Text   string  `yaml:"text"`
+	Align  string  `yaml:"align"`  // L, C or R
+	Border string  `yaml:"border"` // gofpdf border string: "1", "LR", ...
+	Fill   bool    `yaml:"fill"`   // shade with the company fill colour
+	Height float64 `yaml:"height"`
+
+	// header
+	Logo       Box     `yaml:"logo"`
+	Company    Box     `yaml:"company"`
+	ContentTop float64 `yaml:"content_top"` // content starts no higher than this
+
+	// grid, net_pay, contributions, hra_annexure: column widths. Grid rows list one
+	// text per column; BoldColumns are printed bold (the values).
+	Columns     []float64  `yaml:"columns"`
+	BoldColumns []int      `yaml:"bold_columns"`
+	Rows        [][]string `yaml:"rows"`
+
+	// pay_table, contributions, hra_annexure
+	RowHeight    float64 `yaml:"row_height"`
+	HeaderHeight float64 `yaml:"header_height"`
+	TotalsHeight float64 `yaml:"totals_height"`
+	MinRows      int     `yaml:"min_rows"`
+	// KeepWithTotals is how much room after the totals row must fit on
+	// the same page, to keep net pay and footer with it.
+	KeepWithTotals float64   `yaml:"keep_with_totals"`
+	Widths         PayWidths `yaml:"widths"`
+	YTDWidths      PayWidths `yaml:"ytd_widths"` // used with year-to-date columns
+
+	// Labels override the section's built-in captions, by key (see
+	// defaultLabels).
+	Labels map[string]string `yaml:"labels"`
+}
+
+// Box places a block on the page.
+type Box struct {
+	X     float64 `yaml:"x"`
+	Y     float64 `yaml:"y"`
+	Width float64 `yaml:"width"`
+}
+
+// PayWidths are the column widths of the pay table.
+type PayWidths struct {
+	Earning         float64 `yaml:"earning"`
+	Rate            float64 `yaml:"rate"`
+	Amount          float64 `yaml:"amount"`
+	YTD             float64 `yaml:"ytd"`
+	Deduction       float64 `yaml:"deduction"`
+	DeductionAmount float64 `yaml:"deduction_amount"`
+}
+
+// defaultLabels are the built-in captions of each section type.
+var defaultLabels = map[string]map[string]string{
+	SectionPayTable: {
+		"earnings":         "Earnings",
+		"rate":             "Standard\nRate", // one line per row of the header
+		"amount":           "Amount",
+		"ytd":              "YTD",
+		"deductions":       "Deductions",
+		"total":            "Total",
+		"gross":            "Gross Earnings",
+		"total_deductions": "Total Deductions",
+	},
+	SectionNetPay: {
+		"net_pay": "NET PAY",
+	},
+	SectionContributions: {
+		"title":  "Employer Contributions",
+		"amount": "Amount",
+		"total":  "Total Employer Contributions",
+	},
+	SectionHRAAnnexure: {
+		"title":           "Annexure: HRA Exemption u/s 10(13A)", // followed by the FY
+		"note":            "{Name} - exempt amount is the least of HRA received, rent less 10% of basic, and 50% (metro) / 40% of basic.",
+		"month":           "Month",
+		"hra":             "HRA Received",
+		"rent":            "Rent Paid",
+		"rent_less_basic": "Rent - 10% Basic",
+		"basic_share":     "50% / 40% Basic",
+		"exempt":          "Exempt",
+		"total":           "Total HRA Exemption",
+	},
+}
+
+func (s *Section) label(key string) string {
+	if l, ok := s.Labels[key]; ok {
+		return l
+	}
+	return defaultLabels[s.Type][key]
+}
+
+// LayoutNames lists the built-in layouts.
+func LayoutNames() []string {
+	entries, _ := builtinLayouts.ReadDir("layouts")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	return names
+}
+
+// DefaultLayout returns the standard payslip layout.
+func DefaultLayout() *Layout {
+	l, err := LoadLayout("default")
+	if err != nil {
+		panic(err) // the built-in layouts are known to be valid
+	}
+	return l
+}
+
+// LoadLayout loads a built-in layout by name (see LayoutNames) or a layout
+// file by path.
+func LoadLayout(nameOrPath string) (*Layout, error) {
+	data, err := builtinLayouts.ReadFile("layouts/" + nameOrPath + ".yaml")
+	if err != nil {
+		if data, err = os.ReadFile(nameOrPath); err != nil {
+			return nil, fmt.Errorf("layout %q is neither built in (%s) nor a readable file: %w",
+				nameOrPath, strings.Join(LayoutNames(), ", "), err)
+		}
+	}
+	var l Layout
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("parse layout %s: %w", nameOrPath, err)
+	}
+	if err := l.validate(); err != nil {
+		return nil, fmt.Errorf("layout %s: %w", nameOrPath, err)
+	}
+	return &l, nil
+}
+
+func (l *Layout) validate() error {
+	if l.Font == "" {
+		l.Font = "Arial"
+	}
+	if l.Left == 0 {
+		l.Left = 10
+	}
+	if l.Width == 0 {
+		l.Width = 190
+	}
+	if len(l.Sections) == 0 {
+		return fmt.Errorf("no sections")
+	}
+	zero := &model.Employee{}
+	for i := range l.Sections {
+		s := &l.Sections[i]
+		where := fmt.Sprintf("section %d (%s)", i+1, s.Type)
+		switch s.Type {
+		case SectionHeader, SectionSpace, SectionFooter:
+		case SectionText:
+			if _, err := expand(s.Text, zero); err != nil {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+		case SectionGrid:
+			if len(s.Columns) == 0 {
+				return fmt.Errorf("%s: no columns", where)
+			}
+			for _, row := range s.Rows {
+				if len(row) > len(s.Columns) {
+					return fmt.Errorf("%s: row %q has more cells than the %d columns", where, row, len(s.Columns))
+				}
+				for _, cell := range row {
+					if _, err := expand(cell, zero); err != nil {
+						return fmt.Errorf("%s: %w", where, err)
+					}
+				}
+			}
+		case SectionPayTable:
+			if s.Widths.Earning == 0 || s.Widths.Deduction == 0 {
+				return fmt.Errorf("%s: widths need earning and deduction", where)
+			}
+			if s.YTDWidths.Earning == 0 {
+				s.YTDWidths = s.Widths
+			}
+			if s.YTDWidths.YTD == 0 {
+				s.YTDWidths.YTD = s.YTDWidths.Amount
+			}
+		case SectionNetPay:
+			if len(s.Columns) != 3 {
+				return fmt.Errorf("%s: want 3 columns (label, amount, words)", where)
+			}
+		case SectionContributions:
+			if len(s.Columns) != 2 {
+				return fmt.Errorf("%s: want 2 columns (name, amount)", where)
+			}
+		case SectionHRAAnnexure:
+			if len(s.Columns) != 6 {
+				return fmt.Errorf("%s: want 6 columns (month, HRA, rent, rent less basic, basic share, exempt)", where)
+			}
+			if _, err := expand(s.label("note"), zero); err != nil {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+		default:
+			return fmt.Errorf("%s: unknown section type", where)
+		}
+		for key := range s.Labels {
+			if _, ok := defaultLabels[s.Type][key]; !ok {
+				return fmt.Errorf("%s: unknown label %q", where, key)
+			}
+		}
+	}
+	return nil
+}
+
+var fieldRef = regexp.MustCompile(`\{([A-Za-z][A-Za-z0-9_]*(?:\.[A-Za-z][A-Za-z0-9_]*)*)\}`)
+
+// expand replaces the {Field} placeholders in text with emp's data.
+func expand(text string, emp *model.Employee) (string, error) {
+	var err error
+	out := fieldRef.ReplaceAllStringFunc(text, func(ref string) string {
+		v, e := fieldValue(emp, ref[1:len(ref)-1])
+		if e != nil && err == nil {
+			err = e
+		}
+		return v
+	})
+	return out, err
+}
+
+// fieldValue formats the model.Employee field or method named by path,
+// following dots into nested structs.
+func fieldValue(emp *model.Employee, path string) (string, error) {
+	v := reflect.ValueOf(emp)
+	for _, name := range strings.Split(path, ".") {
+		if m := v.MethodByName(name); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+			v = m.Call(nil)[0]
+			continue
+		}
+		for v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return "", fmt.Errorf("{%s}: %s has no field %s", path, v.Type(), name)
+		}
+		f := v.FieldByName(name)
+		if !f.IsValid() || !f.CanInterface() {
+			return "", fmt.Errorf("{%s}: %s has no field %s", path, v.Type(), name)
+		}
+		v = f
+	}
+
+	switch x := v.Interface().(type) {
+	case string:
+		return x, nil
+	case time.Time:
+		return model.FormatDate(x), nil
+	case money.Amount:
+		return x.String(), nil
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), nil
+	case bool:
+		if x {
+			return "Yes", nil
+		}
+		return "No", nil
+	default:
+		return fmt.Sprint(x), nil
+	}
+}
@@ -0,0 +1,256 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"pay_slip_generator/pkg/model"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PasswordPattern builds per-employee PDF passwords from a pattern of
+// literal text and {FIELD} or {FIELD:ARG} placeholders, e.g.
+// "{PAN:4}{DOB:DDMM}" for the first four letters of the PAN followed by the
+// day and month of birth. Fields:
+//
+//	PAN, NAME, BANK   the value (NAME without spaces); ARG n keeps the
+//	                  first n characters, -n the last n
+//	DOB, DOJ          the date, ARG being a layout of DD, MM, YY and YYYY
+//	                  (default DDMMYYYY)
+//
+// Letters come out in upper case.
+type PasswordPattern struct {
+	source string
+	parts  []passwordPart
+}
+
+type passwordPart struct {
+	literal string
+	field   string
+	arg     string
+}
+
+var placeholder = regexp.MustCompile(`\{([A-Za-z]+)(?::([^}]*))?\}`)
+
+// ParsePasswordPattern parses and checks a password pattern.
+func ParsePasswordPattern(s string) (*PasswordPattern, error) {
+	p := &PasswordPattern{source: s}
+	rest := s
+	for rest != "" {
+		loc := placeholder.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			p.parts = append(p.parts, passwordPart{literal: rest})
+			break
+		}
+		if loc[0] > 0 {
+			p.parts = append(p.parts, passwordPart{literal: rest[:loc[0]]})
+		}
+		part := passwordPart{field: strings.ToUpper(rest[loc[2]:loc[3]])}
+		if loc[4] >= 0 {
+			part.arg = rest[loc[4]:loc[5]]
+		}
+		if err := part.check(); err != nil {
+			return nil, fmt.Errorf("password pattern %q: %w", s, err)
+		}
+		p.parts = append(p.parts, part)
+		rest = rest[loc[1]:]
+	}
+	if !p.hasField() {
+		return nil, fmt.Errorf("password pattern %q has no employee field, so every payslip would share one password", s)
+	}
+	return p, nil
+}
+
+func (p *PasswordPattern) hasField() bool {
+	for _, part := range p.parts {
+		if part.field != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (part passwordPart) check() error {
+	switch part.field {
+	case "PAN", "NAME", "BANK":
+		if part.arg != "" {
+			if n, err := strconv.Atoi(part.arg); err != nil || n == 0 {
+				return fmt.Errorf("{%s:%s}: want a character count such as 4 or -4", part.field, part.arg)
+			}
+		}
+	case "DOB", "DOJ":
+		if part.arg != "" && dateLayout(part.arg) == "" {
+			return fmt.Errorf("{%s:%s}: want a date layout of DD, MM, YY and YYYY", part.field, part.arg)
+		}
+	default:
+		return fmt.Errorf("unknown field {%s}", part.field)
+	}
+	return nil
+}
+
+// dateLayout converts e.g. "DDMM" to a Go time layout, or returns "" when
+// s is not made of DD, MM, YY and YYYY.
+func dateLayout(s string) string {
+	var b strings.Builder
+	for rest := strings.ToUpper(s); rest != ""; {
+		switch {
+		case strings.HasPrefix(rest, "YYYY"):
+			b.WriteString("2006")
+			rest = rest[4:]
+		case strings.HasPrefix(rest, "YY"):
+			b.WriteString("06")
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "DD"):
+			b.WriteString("02")
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "MM"):
+			b.WriteString("01")
+			rest = rest[2:]
+		default:
+			return ""
+		}
+	}
+	return b.String()
+}
+
+// String returns the pattern as written.
+func (p *PasswordPattern) String() string { return p.source }
+
+// Password returns emp's password. It fails when a field the pattern needs
+// is empty, or too short for the characters it asks for, rather than fall
+// back to a guessable password.
+func (p *PasswordPattern) Password(emp *model.Employee) (string, error) {
+	var b strings.Builder
+	for _, part := range p.parts {
+		if part.field == "" {
+			b.WriteString(part.literal)
+			continue
+		}
+		s, err := part.value(emp)
+		if err != nil {
+			return "", fmt.Errorf("PDF password for %s: %w", emp.Name, err)
+		}
+		b.WriteString(s)
+	}
+	if !isPrintable(b.String()) {
+		return "", fmt.Errorf("PDF password for %s: only Latin-1 characters can be used", emp.Name)
+	}
+	return b.String(), nil
+}
+
+func (part passwordPart) value(emp *model.Employee) (string, error) {
+	var date time.Time
+	var text string
+	switch part.field {
+	case "DOB":
+		date = emp.DOB
+	case "DOJ":
+		date = emp.DOJ
+	case "PAN":
+		text = emp.PAN
+	case "NAME":
+		text = strings.Join(strings.Fields(emp.Name), "")
+	case "BANK":
+		text = emp.BankAcNo
+	}
+
+	if part.field == "DOB" || part.field == "DOJ" {
+		if date.IsZero() {
+			return "", fmt.Errorf("no %s", part.field)
+		}
+		layout := "02012006"
+		if part.arg != "" {
+			layout = dateLayout(part.arg)
+		}
+		return date.Format(layout), nil
+	}
+
+	text = strings.ToUpper(strings.TrimSpace(text))
+	if text == "" {
+		return "", fmt.Errorf("no %s", part.field)
+	}
+	if part.arg == "" {
+		return text, nil
+	}
+	n, _ := strconv.Atoi(part.arg)
+	r := []rune(text)
+	if len(r) < max(n, -n) {
+		return "", fmt.Errorf("%s is shorter than %d characters", part.field, max(n, -n))
+	}
+	if n > 0 {
+		return string(r[:n]), nil
+	}
+	return string(r[len(r)+n:]), nil
+}
+
+// Describe explains the pattern in words, for telling employees how to open
+// their payslip without giving the password away, e.g. "the first 4
+// characters of your PAN in capitals followed by your date of birth as
+// DDMM".
+func (p *PasswordPattern) Describe() string {
+	var parts []string
+	for _, part := range p.parts {
+		if part.field == "" {
+			parts = append(parts, fmt.Sprintf("%q", part.literal))
+			continue
+		}
+		parts = append(parts, part.describe())
+	}
+	return strings.Join(parts, " followed by ")
+}
+
+func (part passwordPart) describe() string {
+	names := map[string]string{
+		"PAN":  "PAN",
+		"NAME": "name without spaces",
+		"BANK": "bank account number",
+		"DOB":  "date of birth",
+		"DOJ":  "date of joining",
+	}
+	name := names[part.field]
+	switch part.field {
+	case "DOB", "DOJ":
+		arg := part.arg
+		if arg == "" {
+			arg = "DDMMYYYY"
+		}
+		return fmt.Sprintf("your %s as %s", name, strings.ToUpper(arg))
+	}
+	s := "your " + name
+	if part.arg != "" {
+		n, _ := strconv.Atoi(part.arg)
+		if n > 0 {
+			s = fmt.Sprintf("the first %d characters of %s", n, s)
+		} else {
+			s = fmt.Sprintf("the last %d characters of %s", -n, s)
+		}
+	}
+	if part.field != "BANK" {
+		s += " in capitals"
+	}
+	return s
+}
+
+// ownerPassword derives the owner password from secret and the user
+// password, so the same payslip encrypts to the same bytes on every run
+// (gofpdf picks a random one otherwise) and the delivery journal still
+// recognises it.
+func ownerPassword(secret, user string) string {
+	sum := sha256.Sum256([]byte(secret + "\x00" + user))
+	return hex.EncodeToString(sum[:16])
+}
+
+// isPrintable reports whether s can be typed as a PDF password; the
+// standard security handler only takes Latin-1.
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxLatin1 || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
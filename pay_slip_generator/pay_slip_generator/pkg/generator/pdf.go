@@ -1,245 +1,724 @@
 package generator
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"path/filepath"
+	"pay_slip_generator/pkg/company"
+	"pay_slip_generator/pkg/history"
 	"pay_slip_generator/pkg/model"
-	"strconv"
+	"pay_slip_generator/pkg/money"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/jung-kurt/gofpdf"
 )
 
-func getDaysInMonth(month, year string) int {
-	// Try parsing full month name first (e.g., "March 2024")
-	t, err := time.Parse("January 2006", fmt.Sprintf("%s %s", month, year))
+// Options control how payslips are generated.
+type Options struct {
+	// Password, when set, encrypts each payslip with the employee's
+	// password. The PDF can be printed but not edited.
+	Password *PasswordPattern
+	// OwnerSecret is mixed into the owner (permissions) password of
+	// encrypted payslips, and required with Password: without it anyone
+	// who knows the user password could derive the owner password.
+	OwnerSecret string
+	// Companies supply the letterhead, picked by the employee's Entity;
+	// nil prints company.Default().
+	Companies *company.Profiles
+	// Layout is the page design; nil uses DefaultLayout().
+	Layout *Layout
+	// Fonts are the TrueType fonts to print with; without a base font
+	// (nil included) payslips use the layout's core font.
+	Fonts *FontSet
+	// Translations, when set, print labels bilingually.
+	Translations *Translations
+}
+
+// GeneratePaySlip creates a PDF pay slip for the given employee.
+func GeneratePaySlip(emp model.Employee, outputDir string, opts Options) error {
+	profile, err := opts.Companies.For(emp.Entity)
 	if err != nil {
-		// Fallback to abbreviated month name (e.g., "Mar 2024", "Feb 2024")
-		t, err = time.Parse("Jan 2006", fmt.Sprintf("%s %s", month, year))
+		return err
+	}
+	layout := opts.Layout
+	if layout == nil {
+		layout = DefaultLayout()
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	if opts.Password != nil {
+		if opts.OwnerSecret == "" {
+			return fmt.Errorf("encrypted payslips need an owner password secret")
+		}
+		password, err := opts.Password.Password(&emp)
 		if err != nil {
-			return 30 // Default fallback
+			return err
 		}
+		pdf.SetProtection(gofpdf.CnProtectPrint, password, ownerPassword(opts.OwnerSecret, password))
 	}
-	// Go to the first day of the next month, then subtract one day to get the last day of the current month
-	return t.AddDate(0, 1, 0).Add(-24 * time.Hour).Day()
+	// Fixed metadata so that the same payslip always comes out byte for
+	// byte the same, which lets the delivery journal recognise it.
+	period, _ := emp.PeriodStart()
+	pdf.SetCreationDate(period)
+	pdf.SetModificationDate(period)
+	pdf.SetCatalogSort(true)
+	pdf.AddPage()
+	pdf.SetDrawColor(0, 0, 0) // Black borders
+	pdf.SetLineWidth(0.3)
+
+	r := &renderer{
+		pdf:     pdf,
+		emp:     &emp,
+		profile: profile,
+		layout:  layout,
+		fonts:   opts.Fonts,
+		tr:      opts.Translations,
+		primary: profile.Colors.Primary.Or(company.DefaultPrimary),
+		fill:    profile.Colors.Fill.Or(company.DefaultFill),
+	}
+	if r.fonts.Unicode() {
+		r.registered = make(map[string]bool)
+	} else {
+		r.core = pdf.UnicodeTranslatorFromDescriptor("") // cp1252
+	}
+	for i := range layout.Sections {
+		if err := r.draw(&layout.Sections[i]); err != nil {
+			return fmt.Errorf("layout %s, section %d (%s): %w", layout.Name, i+1, layout.Sections[i].Type, err)
+		}
+	}
+
+	// Write file
+	return pdf.OutputFileAndClose(OutputPath(emp, outputDir))
 }
 
-// GeneratePaySlip creates a PDF pay slip for the given employee.
-func GeneratePaySlip(emp model.Employee, outputDir string) error {
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
+// renderer draws the sections of a layout for one employee.
+type renderer struct {
+	pdf     *gofpdf.Fpdf
+	emp     *model.Employee
+	profile *company.Profile
+	layout  *Layout
+
+	primary, fill company.Color
+
+	fonts      *FontSet
+	tr         *Translations
+	registered map[string]bool     // font families added to pdf
+	shaper     shaper              // for script fonts
+	core       func(string) string // UTF-8 to cp1252, for the core font
+	style      string              // current font style and size
+	size       float64
+}
+
+func (r *renderer) draw(s *Section) error {
+	switch s.Type {
+	case SectionHeader:
+		return r.header(s)
+	case SectionText:
+		r.text(s)
+	case SectionGrid:
+		r.grid(s)
+	case SectionPayTable:
+		r.payTable(s)
+	case SectionNetPay:
+		r.netPay(s)
+	case SectionContributions:
+		r.contributions(s)
+	case SectionSpace:
+		r.pdf.Ln(s.Height)
+	case SectionFooter:
+		r.footer(s)
+	case SectionHRAAnnexure:
+		if len(r.emp.HRAExemptionMonths) > 0 {
+			r.hraAnnexure(s)
+		}
+	}
+	return nil
+}
+
+// font sets the font style and size for the text that follows; size 0
+// means def.
+func (r *renderer) font(style string, size, def float64) {
+	if size == 0 {
+		size = def
+	}
+	r.style, r.size = style, size
+	if r.fonts.Unicode() {
+		r.use(r.fonts.base)
+	} else {
+		r.pdf.SetFont(r.layout.Font, style, size)
+	}
+}
 
-	// --- Styles ---
-	pdf.SetFont("Arial", "", 10) // Basic font
+// use switches to fam in the current style and size, adding it to the
+// document on first use. Only the base font is printed as PDF text.
+func (r *renderer) use(fam *fontFamily) {
+	if !r.registered[fam.name] {
+		r.pdf.AddUTF8FontFromBytes(fam.name, "", fam.regular)
+		r.pdf.AddUTF8FontFromBytes(fam.name, "B", fam.bold)
+		r.registered[fam.name] = true
+	}
+	r.pdf.SetFont(fam.name, r.style, r.size)
+}
 
-	// --- Header ---
-	// Logo
-	// Using the provided logo image "logo.png"
-	// Adjust coordinates and width (40mm) as needed to match the look
-	pdf.Image("logo.png", 15, 10, 40, 0, false, "", 0, "")
+// coreText converts text for the core font, printing characters it has no
+// glyph for as "?".
+func (r *renderer) coreText(text string) string {
+	out := []byte(r.core(text))
+	i := 0
+	for _, c := range text {
+		if c >= 0x80 && out[i] == '.' {
+			out[i] = '?'
+		}
+		i++
+	}
+	return string(out)
+}
 
-	// Reset Color (just in case)
+// cell draws text in a w by h cell like gofpdf's CellFormat, switching
+// fonts within the text where its scripts need different ones. Characters
+// no font has are printed as "?".
+func (r *renderer) cell(w, h float64, text, border string, ln int, align string, fill bool) {
+	pdf := r.pdf
+	if !r.fonts.Unicode() {
+		pdf.CellFormat(w, h, r.coreText(text), border, ln, align, fill, 0, "")
+		return
+	}
+	text = r.fonts.printable(text)
+	runs, _ := r.fonts.runs(text)
+	if len(runs) == 0 || len(runs) == 1 && !runs[0].family.shaped() {
+		r.use(r.fonts.base)
+		pdf.CellFormat(w, h, text, border, ln, align, fill, 0, "")
+		return
+	}
+
+	// Several fonts or a shaped one: draw the frame, then each run at its
+	// offset.
+	x, y := pdf.GetXY()
+	pdf.CellFormat(w, h, "", border, ln, align, fill, 0, "")
+	widths := make([]float64, len(runs))
+	var total float64
+	for i, run := range runs {
+		widths[i] = r.runWidth(run)
+		total += widths[i]
+	}
+	start := x + pdf.GetCellMargin()
+	switch align {
+	case "R":
+		start = x + w - pdf.GetCellMargin() - total
+	case "C":
+		start = x + (w-total)/2
+	}
+	r.use(r.fonts.base)
+	_, fontH := pdf.GetFontSize()
+	baseline := y + h/2 + 0.3*fontH
+	for i, run := range runs {
+		if run.family.shaped() {
+			r.drawShaped(run.family, run.text, start, baseline)
+		} else {
+			pdf.Text(start, baseline, run.text)
+		}
+		start += widths[i]
+	}
+}
+
+// multi draws text line by line like gofpdf's MultiCell, wrapping at w
+// between words.
+func (r *renderer) multi(w, h float64, text, align string) {
+	pdf := r.pdf
+	if !r.fonts.Unicode() {
+		pdf.MultiCell(w, h, r.coreText(text), "", align, false)
+		return
+	}
+	text = r.fonts.printable(text)
+	if runs, _ := r.fonts.runs(text); len(runs) == 0 || len(runs) == 1 && !runs[0].family.shaped() {
+		r.use(r.fonts.base)
+		pdf.MultiCell(w, h, text, "", align, false)
+		return
+	}
+
+	// Several fonts or a shaped one: gofpdf cannot measure them, so wrap
+	// here.
+	x, room := pdf.GetX(), w-2*pdf.GetCellMargin()
+	emit := func(line string) {
+		pdf.SetX(x)
+		r.cell(w, h, line, "", 1, align, false)
+	}
+	for _, para := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Split(para, " ") {
+			next := word
+			if line != "" {
+				next = line + " " + word
+			}
+			if line != "" && r.width(next) > room {
+				emit(line)
+				next = word
+			}
+			line = next
+		}
+		emit(line)
+	}
+}
+
+// width measures text as cell draws it, in the current style and size.
+func (r *renderer) width(text string) float64 {
+	runs, _ := r.fonts.runs(r.fonts.printable(text))
+	var total float64
+	for _, run := range runs {
+		total += r.runWidth(run)
+	}
+	return total
+}
+
+// runWidth measures one run in the current style and size; it leaves the
+// base font selected.
+func (r *renderer) runWidth(run textRun) float64 {
+	r.use(r.fonts.base)
+	if run.family.shaped() {
+		return r.shapedWidth(run.family, run.text)
+	}
+	return r.pdf.GetStringWidth(run.text)
+}
+
+// label returns the text of a layout label, with its translation when
+// printing bilingually.
+func (r *renderer) label(text string) string {
+	return r.tr.apply(text)
+}
+
+// currency prefixes net pay: the rupee sign needs a Unicode font.
+func (r *renderer) currency() string {
+	if r.fonts.Unicode() {
+		return "₹ "
+	}
+	return ""
+}
+
+// expand fills in placeholders; layouts are checked when loaded, so
+// errors cannot happen here.
+func (r *renderer) expand(text string) string {
+	s, _ := expand(text, r.emp)
+	return s
+}
+
+// pad indents left-aligned cell text off the border.
+func pad(text string) string {
+	if text == "" {
+		return ""
+	}
+	return " " + text
+}
+
+// fits reports whether height more millimetres fit above the bottom margin.
+func (r *renderer) fits(height float64) bool {
+	_, pageH := r.pdf.GetPageSize()
+	_, _, _, bottomMargin := r.pdf.GetMargins()
+	return r.pdf.GetY()+height <= pageH-bottomMargin
+}
+
+// spill starts a new page when height more millimetres will not fit above
+// the bottom margin, closing the table on the current page.
+func (r *renderer) spill(height float64) bool {
+	if r.fits(height) {
+		return false
+	}
+	r.pdf.SetX(r.layout.Left)
+	r.cell(r.layout.Width, 0, "", "T", 1, "L", false)
+	r.pdf.AddPage()
+	return true
+}
+
+// header draws the logo and the company name, address and CIN.
+func (r *renderer) header(s *Section) error {
+	pdf, p := r.pdf, r.profile
+	if len(p.LogoData) > 0 && s.Logo.Width > 0 {
+		imgOpts := gofpdf.ImageOptions{ImageType: p.LogoType}
+		pdf.RegisterImageOptionsReader("logo", imgOpts, bytes.NewReader(p.LogoData))
+		pdf.ImageOptions("logo", s.Logo.X, s.Logo.Y, s.Logo.Width, 0, false, imgOpts, 0, "")
+		if err := pdf.Error(); err != nil {
+			return fmt.Errorf("company logo: %w", err)
+		}
+	}
+
+	size := s.Size
+	if size == 0 {
+		size = 10
+	}
+	pdf.SetXY(s.Company.X, s.Company.Y)
+	pdf.SetTextColor(r.primary.R, r.primary.G, r.primary.B)
+	r.font("B", size, 10)
+	r.cell(s.Company.Width, size/2, p.Name, "", 0, "L", false)
+	pdf.Ln(size / 2)
+	pdf.SetTextColor(0, 0, 0)
+	r.font("", size-1, 9)
+	address := strings.Join(p.Address, "\n")
+	if p.CIN != "" {
+		address += "\nCIN: " + p.CIN
+	}
+	if address != "" {
+		pdf.SetX(s.Company.X)
+		r.multi(s.Company.Width, (size-1)*4/9, address, "L")
+	}
+	pdf.SetY(max(s.ContentTop, pdf.GetY()+5)) // Space before content
+	return nil
+}
+
+// text draws one line the width of the content, e.g. a shaded title.
+func (r *renderer) text(s *Section) {
+	pdf := r.pdf
+	style := ""
+	if s.Bold {
+		style = "B"
+	}
+	r.font(style, s.Size, 9)
+	if s.Color == "primary" {
+		pdf.SetTextColor(r.primary.R, r.primary.G, r.primary.B)
+	}
+	pdf.SetFillColor(r.fill.R, r.fill.G, r.fill.B)
+	align := s.Align
+	if align == "" {
+		align = "L"
+	}
+	text := r.expand(r.label(s.Text))
+	if align == "L" {
+		text = pad(text)
+	}
+	pdf.SetX(r.layout.Left)
+	r.cell(r.layout.Width, s.Height, text, s.Border, 1, align, s.Fill)
 	pdf.SetTextColor(0, 0, 0)
+}
 
-	// Company Address (Right Aligned)
-	// Align closer to right margin (A4 width 210, margin 10/15 -> ~195)
-	pdf.SetXY(110, 15)
-	pdf.SetFont("Arial", "B", 10)
-	pdf.Cell(85, 5, "AbegaTech Pvt. Ltd.")
-	pdf.Ln(5)
-	pdf.SetX(110)
-	pdf.SetFont("Arial", "", 9)
-	pdf.MultiCell(85, 4, "P No 147, Floor 1 Rd No7, Sri Madhavam,\nMadeenaguda, Miyapur, Hyderabad 500049", "", "L", false)
-
-	pdf.SetY(40) // Space before content
-
-	// --- Title Box ---
-	// Grey background title
-	pdf.SetFillColor(230, 230, 230)
-	pdf.SetDrawColor(0, 0, 0) // Black borders
-	pdf.SetFont("Arial", "", 10)
-	pdf.SetLineWidth(0.3)
+// grid draws rows of cells boxed as one block.
+func (r *renderer) grid(s *Section) {
+	pdf := r.pdf
+	for i, row := range s.Rows {
+		pdf.SetX(r.layout.Left)
+		for j, width := range s.Columns {
+			cell := ""
+			if j < len(row) {
+				cell = r.expand(r.label(row[j]))
+			}
+			border := ""
+			if j == 0 {
+				border += "L"
+			}
+			if j == len(s.Columns)-1 {
+				border += "R"
+			}
+			if i == len(s.Rows)-1 {
+				border += "B"
+			}
+			style := ""
+			if slices.Contains(s.BoldColumns, j) {
+				style = "B"
+			}
+			r.font(style, s.Size, 9)
+			ln := 0
+			if j == len(s.Columns)-1 {
+				ln = 1
+			}
+			r.cell(width, s.Height, pad(cell), border, ln, "L", false)
+		}
+	}
+}
+
+// payTable draws earnings beside deductions, then their totals. With pay
+// history each side gets a year-to-date column.
+func (r *renderer) payTable(s *Section) {
+	pdf, emp := r.pdf, r.emp
+	showYTD := emp.YTD.Components != nil
+	w := s.Widths
+	amtBorder := "BTR"
+	if showYTD {
+		w = s.YTDWidths
+		amtBorder = "BT"
+	}
+	size := s.Size
+	if size == 0 {
+		size = 9
+	}
+
+	drawTableHeader := func() {
+		r.font("B", size, 9)
+		pdf.SetX(r.layout.Left)
+		r.cell(w.Earning, s.HeaderHeight, pad(r.label(s.label("earnings"))), "LBT", 0, "L", false)
+
+		// Rate caption, one header row per line; translated, it is
+		// English over the translation.
+		origX, origY := pdf.GetX(), pdf.GetY()
+		r.cell(w.Rate, s.HeaderHeight, "", "BT", 0, "C", false) // Frame
+		lines := strings.Split(s.label("rate"), "\n")
+		if tr, ok := r.tr.lookup(s.label("rate")); ok {
+			lines = []string{strings.ReplaceAll(s.label("rate"), "\n", " "), tr}
+		}
+		lineH := s.HeaderHeight / float64(len(lines))
+		if len(lines) > 1 {
+			r.font("B", size-1, 8)
+		}
+		for i, line := range lines {
+			pdf.SetXY(origX, origY+lineH*float64(i))
+			r.cell(w.Rate, lineH, line, "", 0, "C", false)
+		}
+		pdf.SetXY(origX+w.Rate, origY) // Next col
+		r.font("B", size, 9)
 
-	// Payslip for : Month Year (Right Aligned in the box)
-	// Detailed Grid border box begins
-	pdf.SetX(10)
-	pdf.CellFormat(190, 7, fmt.Sprintf("Payslip for : %s %s", emp.Month, emp.Year), "1", 1, "R", true, 0, "")
-
-	// --- Employee Details Grid ---
-	pdf.SetFont("Arial", "", 9)
-	h := 7.0 // Row height
-
-	// Widths: Label 25, Value 65, Label 30, Value 70 = 190 total
-
-	// Row 1
-	pdf.SetX(10)
-	pdf.SetFont("Arial", "", 9)
-	pdf.CellFormat(25, h, " Emp Name", "L", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "B", 9)
-	pdf.CellFormat(65, h, emp.Name, "", 0, "L", false, 0, "")
-
-	pdf.SetFont("Arial", "", 9)
-	pdf.CellFormat(30, h, " DOJ", "", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "B", 9)
-	pdf.CellFormat(70, h, "  "+emp.DOJ, "R", 1, "L", false, 0, "")
-
-	// Row 2
-	pdf.SetX(10)
-	pdf.SetFont("Arial", "", 9)
-	pdf.CellFormat(25, h, " Designation", "L", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "B", 9)
-	pdf.CellFormat(65, h, emp.Designation, "", 0, "L", false, 0, "")
-
-	pdf.SetFont("Arial", "", 9)
-	pdf.CellFormat(30, h, " Gender", "", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "B", 9)
-	pdf.CellFormat(70, h, "  "+emp.Gender, "R", 1, "L", false, 0, "")
-
-	// Row 3 (Bottom border for first block removed to merge with next)
-	pdf.SetX(10)
-	pdf.SetFont("Arial", "", 9)
-	pdf.CellFormat(25, h, " Bank Ac. No.", "L", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "B", 9)
-	pdf.CellFormat(65, h, emp.BankAcNo, "", 0, "L", false, 0, "")
-
-	pdf.SetFont("Arial", "", 9)
-	pdf.CellFormat(30, h, " UAN", "", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "B", 9)
-	pdf.CellFormat(70, h, "  "+emp.UAN, "R", 1, "L", false, 0, "")
-
-	// Row 4 (New)
-	pdf.SetX(10)
-	pdf.SetFont("Arial", "", 9)
-	pdf.CellFormat(25, h, " PAN", "LB", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "B", 9)
-	pdf.CellFormat(65, h, emp.PAN, "B", 0, "L", false, 0, "")
-
-	pdf.SetFont("Arial", "", 9)
-	pdf.CellFormat(30, h, " PF No", "B", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "B", 9)
-	pdf.CellFormat(70, h, "  "+emp.PFNo, "RB", 1, "L", false, 0, "")
-
-	// --- Attendance Info ---
-	// Grey background
-	pdf.SetFillColor(230, 230, 230)
-	pdf.SetFont("Arial", "", 9)
-	pdf.SetX(10)
-
-	// Dynamic Days Calculation
-	stdDays := getDaysInMonth(emp.Month, emp.Year)
-	lopDays := 0.0
-	if val, err := strconv.ParseFloat(emp.LOPDays, 64); err == nil {
-		lopDays = val
-	}
-	payableDays := float64(stdDays) - lopDays
-
-	attendanceText := fmt.Sprintf("Standard Days: %d          Payable days: %.0f          Loss of Pay Days : %s", stdDays, payableDays, emp.LOPDays)
-	pdf.CellFormat(190, 7, attendanceText, "1", 1, "L", true, 0, "")
-
-	// --- Earnings & Deductions Tables ---
-	pdf.SetFont("Arial", "B", 9)
-
-	// Header
-	pdf.SetX(10)
-	pdf.CellFormat(55, 8, " Earnings", "LBT", 0, "L", false, 0, "")
-
-	// Stacked Header trick using XY reset or just simple line
-	// "Standard Rate" logic
-	origX, origY := pdf.GetX(), pdf.GetY()
-	pdf.CellFormat(20, 8, "", "BT", 0, "C", false, 0, "") // Frame
-	pdf.SetXY(origX, origY)
-	pdf.SetFont("Arial", "B", 8)
-	pdf.CellFormat(20, 4, "Standard", "", 0, "C", false, 0, "") // Top
-	pdf.SetXY(origX, origY+4)
-	pdf.CellFormat(20, 4, "Rate", "", 0, "C", false, 0, "") // Bottom
-	pdf.SetXY(origX+20, origY)                              // Next col
-	pdf.SetFont("Arial", "B", 9)
-
-	pdf.CellFormat(20, 8, "Amount", "BTR", 0, "R", false, 0, "")
-
-	// Deductions Header
-	pdf.CellFormat(55, 8, " Deductions", "BT", 0, "L", false, 0, "")
-	pdf.CellFormat(40, 8, "Total", "BTR", 1, "R", false, 0, "")
-
-	// --- Table Content ---
-	pdf.SetFont("Arial", "", 9)
-
-	// Helper for rows
-	drawRow := func(earnLabel string, earnRate, earnAmt float64, dedLabel string, dedAmt float64) {
-		pdf.SetX(10)
+		r.cell(w.Amount, s.HeaderHeight, r.label(s.label("amount")), amtBorder, 0, "R", false)
+		if showYTD {
+			r.cell(w.YTD, s.HeaderHeight, r.label(s.label("ytd")), "BTR", 0, "R", false)
+		}
+
+		// Deductions Header
+		r.cell(w.Deduction, s.HeaderHeight, pad(r.label(s.label("deductions"))), "BT", 0, "L", false)
+		r.cell(w.DeductionAmount, s.HeaderHeight, r.label(s.label("total")), amtBorder, 0, "R", false)
+		if showYTD {
+			r.cell(w.YTD, s.HeaderHeight, r.label(s.label("ytd")), "BTR", 0, "R", false)
+		}
+		pdf.Ln(-1)
+
+		r.font("", size, 9)
+	}
+	drawTableHeader()
+
+	// Helper for rows; nil components leave their half of the row empty.
+	rowH := s.RowHeight
+	drawRow := func(earn, ded *model.Component) {
+		pdf.SetX(r.layout.Left)
 
 		// Earnings
-		pdf.CellFormat(55, 6, " "+earnLabel, "L", 0, "L", false, 0, "")
+		var earnLabel string
+		var earnRate, earnAmt money.Amount
+		if earn != nil {
+			earnLabel, earnRate, earnAmt = earn.Name, earn.Rate, earn.Amount
+		}
+		r.cell(w.Earning, rowH, pad(r.label(earnLabel)), "L", 0, "L", false)
 
 		rateStr := ""
 		if earnRate > 0 {
-			rateStr = fmt.Sprintf("%.2f", earnRate)
+			rateStr = earnRate.String()
 		}
-		pdf.CellFormat(20, 6, rateStr, "", 0, "R", false, 0, "")
+		r.cell(w.Rate, rowH, rateStr, "", 0, "R", false)
 
 		amtStr := ""
 		if earnAmt > 0 || earnRate > 0 {
-			amtStr = fmt.Sprintf("%.2f", earnAmt)
+			amtStr = earnAmt.String()
+		}
+		if showYTD {
+			ytdStr := ""
+			if earn != nil && amtStr != "" {
+				ytdStr = emp.YTDAmount(*earn).String()
+			}
+			r.cell(w.Amount, rowH, amtStr, "", 0, "R", false)
+			r.cell(w.YTD, rowH, ytdStr, "R", 0, "R", false)
+		} else {
+			r.cell(w.Amount, rowH, amtStr, "R", 0, "R", false)
 		}
-		pdf.CellFormat(20, 6, amtStr, "R", 0, "R", false, 0, "")
 
 		// Deductions
-		lbl := " " + dedLabel
-		if dedAmt == 0 {
-			lbl = ""
+		lbl, dedStr, ytdStr := "", "", ""
+		if ded != nil && ded.Amount != 0 {
+			lbl = pad(r.label(ded.Name))
+			dedStr = ded.Amount.String()
+			ytdStr = emp.YTDAmount(*ded).String()
 		}
-		pdf.CellFormat(55, 6, lbl, "", 0, "L", false, 0, "")
-
-		dedStr := ""
-		if dedAmt > 0 {
-			dedStr = fmt.Sprintf("%.2f", dedAmt)
+		r.cell(w.Deduction, rowH, lbl, "", 0, "L", false)
+		if showYTD {
+			r.cell(w.DeductionAmount, rowH, dedStr, "", 0, "R", false)
+			r.cell(w.YTD, rowH, ytdStr, "R", 1, "R", false)
+		} else {
+			r.cell(w.DeductionAmount, rowH, dedStr, "R", 1, "R", false)
 		}
-		pdf.CellFormat(40, 6, dedStr, "R", 1, "R", false, 0, "")
 	}
 
-	// Rows
-	drawRow("Basic Pay", emp.BasicPayRate, emp.BasicPayAmount, "Professional Tax", emp.ProfessionalTax)
-	drawRow("House Rent Allowance", emp.HRARate, emp.HRAAmount, "Provident Fund", emp.PF)
-
-	if emp.HasIncomeTax {
-		drawRow("Other Allowance", emp.OtherAllowanceRate, emp.OtherAllowanceAmount, "Income Tax", emp.IncomeTax)
-	} else {
-		drawRow("Other Allowance", emp.OtherAllowanceRate, emp.OtherAllowanceAmount, "", 0.0)
+	// Rows: as many as the longer of the two columns, padded to the
+	// layout's minimum table height.
+	earnings := emp.Earnings()
+	deductions := emp.Deductions()
+	for i := range max(len(earnings), len(deductions), s.MinRows) {
+		if r.spill(rowH) {
+			drawTableHeader()
+		}
+		var earn, ded *model.Component
+		if i < len(earnings) {
+			earn = &earnings[i]
+		}
+		if i < len(deductions) {
+			ded = &deductions[i]
+		}
+		drawRow(earn, ded)
 	}
-	// Empty rows
-	drawRow("", 0, 0, "", 0)
-	drawRow("", 0, 0, "", 0)
+
+	// Keep totals with what follows them (net pay and footer).
+	r.spill(s.TotalsHeight + s.KeepWithTotals)
 
 	// --- Totals ---
-	pdf.SetFont("Arial", "B", 9)
-	pdf.SetX(10)
+	h := s.TotalsHeight
+	r.font("B", size, 9)
+	pdf.SetX(r.layout.Left)
 	// Gross Earnings
-	pdf.CellFormat(55, 8, " Gross Earnings", "LTB", 0, "L", false, 0, "")
-	pdf.CellFormat(20, 8, fmt.Sprintf("%.2f", emp.GrossEarnings), "TB", 0, "R", false, 0, "")
-	pdf.CellFormat(20, 8, fmt.Sprintf("%.2f", emp.GrossEarnings), "TBR", 0, "R", false, 0, "")
+	r.cell(w.Earning, h, pad(r.label(s.label("gross"))), "LTB", 0, "L", false)
+	r.cell(w.Rate, h, emp.GrossEarnings.String(), "TB", 0, "R", false)
+	r.cell(w.Amount, h, emp.GrossEarnings.String(), amtBorder, 0, "R", false)
+	if showYTD {
+		r.cell(w.YTD, h, (emp.YTD.Gross + emp.GrossEarnings).String(), "TBR", 0, "R", false)
+	}
 
 	// Total Deductions
-	pdf.CellFormat(55, 8, " Total Deductions", "TB", 0, "L", false, 0, "")
-	pdf.CellFormat(40, 8, fmt.Sprintf("%.0f", emp.TotalDeductions), "TBR", 1, "R", false, 0, "")
+	r.cell(w.Deduction, h, pad(r.label(s.label("total_deductions"))), "TB", 0, "L", false)
+	r.cell(w.DeductionAmount, h, emp.TotalDeductions.String(), amtBorder, 0, "R", false)
+	if showYTD {
+		r.cell(w.YTD, h, (emp.YTD.TotalDeductions + emp.TotalDeductions).String(), "TBR", 0, "R", false)
+	}
+	pdf.Ln(-1)
+}
 
-	// --- Net Pay ---
-	pdf.SetX(10)
-	pdf.SetFont("Arial", "B", 10)
-	pdf.CellFormat(30, 10, " NET PAY", "LTB", 0, "L", false, 0, "")
-	pdf.CellFormat(25, 10, fmt.Sprintf("%.2f", emp.NetPay), "TB", 0, "L", false, 0, "")
+// netPay draws net pay in figures and in words.
+func (r *renderer) netPay(s *Section) {
+	pdf := r.pdf
+	pdf.SetX(r.layout.Left)
+	r.font("B", s.Size, 10)
+	r.cell(s.Columns[0], s.Height, pad(r.label(s.label("net_pay"))), "LTB", 0, "L", false)
+	r.cell(s.Columns[1], s.Height, r.currency()+r.emp.NetPay.String(), "TB", 0, "L", false)
 
 	// Words
-	pdf.SetFont("Arial", "", 8)
-	words := emp.NetPayInWords()
-	pdf.CellFormat(135, 10, "("+words+")", "TBR", 1, "L", false, 0, "")
+	r.font("", 8, 8)
+	r.cell(s.Columns[2], s.Height, "("+r.emp.NetPayInWords()+")", "TBR", 1, "L", false)
+}
 
-	pdf.Ln(10)
+// contributions lists what the employer pays on top of net pay (part of
+// CTC), kept apart from the deductions.
+func (r *renderer) contributions(s *Section) {
+	pdf, emp := r.pdf, r.emp
+	if len(emp.EmployerContributions) == 0 {
+		return
+	}
+	pdf.Ln(4)
+	if !r.fits(s.HeaderHeight*2 + s.RowHeight*float64(len(emp.EmployerContributions))) {
+		pdf.AddPage()
+	}
 
-	// --- Footer ---
-	pdf.SetX(10)
-	pdf.SetFont("Arial", "", 8)
-	pdf.CellFormat(190, 5, "** This is computer generated payslip and doesn't require signature and stamp", "", 1, "C", false, 0, "")
+	nameW, amtW := s.Columns[0], s.Columns[1]
+	pdf.SetFillColor(r.fill.R, r.fill.G, r.fill.B)
+	pdf.SetX(r.layout.Left)
+	r.font("B", s.Size, 9)
+	r.cell(nameW, s.HeaderHeight, pad(r.label(s.label("title"))), "LTB", 0, "L", true)
+	r.cell(amtW, s.HeaderHeight, r.label(s.label("amount")), "TBR", 1, "R", true)
+
+	r.font("", s.Size, 9)
+	var total money.Amount
+	for _, c := range emp.EmployerContributions {
+		pdf.SetX(r.layout.Left)
+		r.cell(nameW, s.RowHeight, pad(r.label(c.Name)), "L", 0, "L", false)
+		r.cell(amtW, s.RowHeight, c.Amount.String(), "R", 1, "R", false)
+		total += c.Amount
+	}
 
-	// Write file
-	outfile := fmt.Sprintf("%s/%s_%s_%s.pdf", outputDir, emp.Name, emp.Month, emp.Year)
-	return pdf.OutputFileAndClose(outfile)
+	pdf.SetX(r.layout.Left)
+	r.font("B", s.Size, 9)
+	r.cell(nameW, s.HeaderHeight, pad(r.label(s.label("total"))), "LTB", 0, "L", false)
+	r.cell(amtW, s.HeaderHeight, total.String(), "TBR", 1, "R", false)
+}
+
+// footer prints the company footer, or the section's own text.
+func (r *renderer) footer(s *Section) {
+	text := r.profile.Footer
+	if s.Text != "" {
+		text = r.expand(s.Text)
+	}
+	text = r.label(text)
+	align := s.Align
+	if align == "" {
+		align = "C"
+	}
+	r.pdf.SetX(r.layout.Left)
+	r.font("", s.Size, 8)
+	r.multi(r.layout.Width, s.Height, text, align)
+}
+
+// OutputPath returns where GeneratePaySlip writes emp's payslip. The file
+// is named after the employee and pay month, made safe for a file name, and
+// ends in a short digest of the employee's history.Key so that namesakes
+// paid in the same month get a payslip each.
+func OutputPath(emp model.Employee, outputDir string) string {
+	sum := sha256.Sum256([]byte(history.Key(&emp)))
+	name := fmt.Sprintf("%s_%s_%s_%s.pdf", emp.Name, emp.Month, emp.Year, hex.EncodeToString(sum[:4]))
+	return filepath.Join(outputDir, safeFileName(name))
+}
+
+// safeFileName replaces everything but letters, digits, spaces, dots,
+// dashes and underscores, and leading dots, with underscores.
+func safeFileName(name string) string {
+	name = strings.Map(func(c rune) rune {
+		if unicode.IsLetter(c) || unicode.IsMark(c) || unicode.IsDigit(c) || strings.ContainsRune(" .-_", c) {
+			return c
+		}
+		return '_'
+	}, name)
+	if strings.HasPrefix(name, ".") {
+		name = "_" + name[1:]
+	}
+	return name
+}
+
+// hraAnnexure adds a page with the month-by-month HRA exemption that went
+// into the income tax projection.
+func (r *renderer) hraAnnexure(s *Section) {
+	pdf, emp := r.pdf, r.emp
+	pdf.AddPage()
+	first := emp.HRAExemptionMonths[0].Month
+	fyStart := first.Year()
+	if first.Month() < time.April {
+		fyStart--
+	}
+	w := s.Columns
+	var width float64
+	for _, c := range w {
+		width += c
+	}
+
+	pdf.SetX(r.layout.Left)
+	r.font("B", s.Size+2, 11)
+	r.cell(width, s.Height, fmt.Sprintf("%s - FY %d-%02d", r.label(s.label("title")), fyStart, (fyStart+1)%100), "", 1, "L", false)
+	pdf.SetX(r.layout.Left)
+	r.font("", s.Size, 9)
+	r.multi(width, s.RowHeight, r.expand(r.label(s.label("note"))), "L")
+	pdf.Ln(2)
+
+	pdf.SetFillColor(r.fill.R, r.fill.G, r.fill.B)
+	r.font("B", s.Size, 9)
+	pdf.SetX(r.layout.Left)
+	for i, key := range []string{"month", "hra", "rent", "rent_less_basic", "basic_share", "exempt"} {
+		title, align := r.label(s.label(key)), "R"
+		if i == 0 {
+			title, align = pad(title), "L"
+		}
+		r.cell(w[i], s.HeaderHeight, title, "1", 0, align, true)
+	}
+	pdf.Ln(-1)
+
+	r.font("", s.Size, 9)
+	var total money.Amount
+	for _, m := range emp.HRAExemptionMonths {
+		share := "40%"
+		if m.Metro {
+			share = "50%"
+		}
+		pdf.SetX(r.layout.Left)
+		r.cell(w[0], s.RowHeight, pad(m.Month.Format("Jan 2006")), "LR", 0, "L", false)
+		r.cell(w[1], s.RowHeight, m.HRA.String(), "R", 0, "R", false)
+		r.cell(w[2], s.RowHeight, m.Rent.String(), "R", 0, "R", false)
+		r.cell(w[3], s.RowHeight, m.RentLessBasic.String(), "R", 0, "R", false)
+		r.cell(w[4], s.RowHeight, m.BasicShare.String()+" ("+share+")", "R", 0, "R", false)
+		r.cell(w[5], s.RowHeight, m.Exempt.String(), "R", 1, "R", false)
+		total += m.Exempt
+	}
+
+	pdf.SetX(r.layout.Left)
+	r.font("B", s.Size, 9)
+	r.cell(width-w[5], s.TotalsHeight, pad(r.label(s.label("total"))), "1", 0, "L", false)
+	r.cell(w[5], s.TotalsHeight, total.String(), "1", 1, "R", false)
 }
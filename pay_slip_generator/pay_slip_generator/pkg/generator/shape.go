@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+)
+
+// shaper lays out runs of script fonts for one payslip. It is not safe for
+// concurrent use; every renderer has its own.
+type shaper struct {
+	hb    shaping.HarfbuzzShaper
+	faces map[*font.Font]*font.Face
+}
+
+// shape lays out text in fam, bold or not. Glyph positions come out in font
+// units.
+func (s *shaper) shape(fam *fontFamily, bold bool, text string) (shaping.Output, *font.Face) {
+	f := fam.fonts[0]
+	if bold {
+		f = fam.fonts[1]
+	}
+	face := s.faces[f]
+	if face == nil {
+		if s.faces == nil {
+			s.faces = make(map[*font.Font]*font.Face)
+		}
+		face = font.NewFace(f)
+		s.faces[f] = face
+	}
+	runes := []rune(text)
+	script := language.Common
+	for _, r := range runes {
+		if sc := language.LookupScript(r); sc != language.Common && sc != language.Inherited {
+			script = sc
+			break
+		}
+	}
+	out := s.hb.Shape(shaping.Input{
+		Text:      runes,
+		RunStart:  0,
+		RunEnd:    len(runes),
+		Direction: di.DirectionLTR,
+		Face:      face,
+		Size:      fixed.I(int(f.Upem())),
+		Script:    script,
+	})
+	return out, face
+}
+
+// shapedWidth returns the width of text in fam at the current style and
+// size.
+func (r *renderer) shapedWidth(fam *fontFamily, text string) float64 {
+	out, face := r.shaper.shape(fam, r.style == "B", text)
+	return float64(out.Advance) / 64 * r.unitsPerFontUnit(face)
+}
+
+// drawShaped fills the outlines of text in fam, shaped, with its origin at
+// x on the baseline y, in the current text colour. Bold without a Bold font
+// is stroked as well.
+func (r *renderer) drawShaped(fam *fontFamily, text string, x, y float64) {
+	pdf := r.pdf
+	out, face := r.shaper.shape(fam, r.style == "B", text)
+	scale := r.unitsPerFontUnit(face)
+
+	fr, fg, fb := pdf.GetFillColor()
+	pdf.SetFillColor(pdf.GetTextColor())
+	op := "F"
+	if r.style == "B" && fam.fakeBold {
+		dr, dg, db := pdf.GetDrawColor()
+		lw := pdf.GetLineWidth()
+		pdf.SetDrawColor(pdf.GetTextColor())
+		_, size := pdf.GetFontSize()
+		pdf.SetLineWidth(size / 30)
+		defer func() {
+			pdf.SetDrawColor(dr, dg, db)
+			pdf.SetLineWidth(lw)
+		}()
+		op = "FD"
+	}
+	pen := x
+	for _, g := range out.Glyphs {
+		outline, ok := face.GlyphData(g.GlyphID).(font.GlyphOutline)
+		if ok && len(outline.Segments) > 0 {
+			ox := pen + float64(g.XOffset)/64*scale
+			oy := y - float64(g.YOffset)/64*scale
+			pt := func(p font.SegmentPoint) (float64, float64) {
+				return ox + float64(p.X)*scale, oy - float64(p.Y)*scale
+			}
+			var cx, cy float64 // current point
+			for i, seg := range outline.Segments {
+				switch seg.Op {
+				case ot.SegmentOpMoveTo:
+					if i > 0 {
+						pdf.ClosePath()
+					}
+					cx, cy = pt(seg.Args[0])
+					pdf.MoveTo(cx, cy)
+				case ot.SegmentOpLineTo:
+					cx, cy = pt(seg.Args[0])
+					pdf.LineTo(cx, cy)
+				case ot.SegmentOpQuadTo:
+					// PDF has no quadratic curves; raise to a cubic.
+					qx, qy := pt(seg.Args[0])
+					ex, ey := pt(seg.Args[1])
+					pdf.CurveBezierCubicTo(cx+2*(qx-cx)/3, cy+2*(qy-cy)/3, ex+2*(qx-ex)/3, ey+2*(qy-ey)/3, ex, ey)
+					cx, cy = ex, ey
+				case ot.SegmentOpCubeTo:
+					c0x, c0y := pt(seg.Args[0])
+					c1x, c1y := pt(seg.Args[1])
+					cx, cy = pt(seg.Args[2])
+					pdf.CurveBezierCubicTo(c0x, c0y, c1x, c1y, cx, cy)
+				}
+			}
+			pdf.ClosePath()
+			pdf.DrawPath(op)
+		}
+		pen += float64(g.XAdvance) / 64 * scale
+	}
+	pdf.SetFillColor(fr, fg, fb)
+}
+
+// unitsPerFontUnit converts font units of face to document units at the
+// current font size.
+func (r *renderer) unitsPerFontUnit(face *font.Face) float64 {
+	_, size := r.pdf.GetFontSize()
+	return size / float64(face.Upem())
+}
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Translations print payslip labels bilingually: every layout label,
+// caption and component name found in Labels is followed by its
+// translation, e.g. "Earnings / आय". Keys are the English text as written
+// in the layout, placeholders included ("Payslip for : {Month} {Year}").
+// Loaded from YAML or JSON:
+//
+//	language: hi
+//	separator: " / "
+//	labels:
+//	  Earnings: आय
+//	  Basic Pay: मूल वेतन
+//
+// Translations need fonts for their script (see LoadFonts).
+type Translations struct {
+	Language  string            `yaml:"language"`
+	Separator string            `yaml:"separator"` // default " / "
+	Labels    map[string]string `yaml:"labels"`
+}
+
+// LoadTranslations reads a translations file.
+func LoadTranslations(path string) (*Translations, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Translations
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parse translations %s: %w", path, err)
+	}
+	if len(t.Labels) == 0 {
+		return nil, fmt.Errorf("translations %s: no labels", path)
+	}
+	if t.Separator == "" {
+		t.Separator = " / "
+	}
+	return &t, nil
+}
+
+// CheckFonts reports a translation that fonts cannot print, so a run fails
+// up front instead of on every payslip.
+func (t *Translations) CheckFonts(fonts *FontSet) error {
+	if t == nil {
+		return nil
+	}
+	for _, tr := range t.Labels {
+		if !fonts.Unicode() {
+			if strings.IndexFunc(tr, func(r rune) bool { return r >= 0x80 }) >= 0 {
+				return fmt.Errorf("translation %q needs Unicode fonts; add NotoSans-Regular.ttf to the fonts", tr)
+			}
+			continue
+		}
+		if _, err := fonts.runs(tr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup returns the translation of text, if any.
+func (t *Translations) lookup(text string) (string, bool) {
+	if t == nil || text == "" {
+		return "", false
+	}
+	tr, ok := t.Labels[text]
+	return tr, ok && tr != ""
+}
+
+// apply returns text followed by its translation, or text alone.
+func (t *Translations) apply(text string) string {
+	if tr, ok := t.lookup(text); ok {
+		return text + t.Separator + tr
+	}
+	return text
+}
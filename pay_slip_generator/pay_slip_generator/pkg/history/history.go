@@ -0,0 +1,173 @@
+// Package history remembers what every employee was paid in earlier pay runs
+// so that payslips can show year-to-date totals and the TDS projection can
+// use actuals instead of sheet columns.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"pay_slip_generator/pkg/payroll"
+	"strings"
+	"time"
+)
+
+// Entry is one employee's pay for one month, by component name.
+type Entry struct {
+	Period          string                  `json:"period"` // "2025-10"
+	Earnings        map[string]money.Amount `json:"earnings"`
+	Deductions      map[string]money.Amount `json:"deductions"`
+	GrossEarnings   money.Amount            `json:"gross_earnings"`
+	TotalDeductions money.Amount            `json:"total_deductions"`
+	NetPay          money.Amount            `json:"net_pay"`
+}
+
+// Store is a JSON file of pay history, keyed by employee. Recording a month
+// that is already there replaces it, so rerunning a month does not count it
+// twice.
+type Store struct {
+	path      string
+	Employees map[string][]Entry `json:"employees"`
+}
+
+// Open reads the store at path; a missing file is an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, Employees: make(map[string][]Entry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parse history %s: %w", path, err)
+	}
+	if s.Employees == nil {
+		s.Employees = make(map[string][]Entry)
+	}
+	return s, nil
+}
+
+// Save writes the store back to its file, replacing it atomically.
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Key identifies an employee across runs: email, else PAN, else name.
+func Key(emp *model.Employee) string {
+	for _, id := range []string{emp.Email, emp.PAN, emp.Name} {
+		if id = strings.ToLower(strings.TrimSpace(id)); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// Period is the key of a pay month in the store, e.g. "2025-10".
+func Period(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// Apply sets emp.YTD to the totals of the months recorded for emp in the
+// same financial year before its pay month. Employees without history keep
+// the year-to-date values from the sheet.
+func (s *Store) Apply(emp *model.Employee) {
+	ApplyEntries(emp, s.Employees[Key(emp)])
+}
+
+// ApplyEntries sets emp.YTD from entries, one per pay month, as Store.Apply
+// does; entries outside the financial year of emp's pay month are ignored.
+func ApplyEntries(emp *model.Employee, entries []Entry) {
+	month, ok := emp.PeriodStart()
+	if !ok {
+		return
+	}
+	_, remaining := payroll.FinancialYear(month)
+	from, to := Period(month.AddDate(0, remaining-12, 0)), Period(month)
+
+	found := false
+	ytd := model.YTD{Components: make(map[string]money.Amount)}
+	for _, e := range entries {
+		if e.Period < from || e.Period >= to {
+			continue
+		}
+		found = true
+		for name, a := range e.Earnings {
+			ytd.Components[name] += a
+		}
+		for name, a := range e.Deductions {
+			ytd.Components[name] += a
+		}
+		ytd.Gross += e.GrossEarnings
+		ytd.TotalDeductions += e.TotalDeductions
+	}
+	if !found {
+		// Nothing recorded yet this year: keep the sheet's totals, if any.
+		// Without them this is the first month and YTD is the month itself.
+		if emp.YTD.Gross == 0 {
+			emp.YTD.Components = ytd.Components
+		}
+		return
+	}
+	ytd.PF = ytd.Components["Provident Fund"]
+	ytd.ProfessionalTax = ytd.Components["Professional Tax"]
+	ytd.IncomeTax = ytd.Components["Income Tax"]
+	emp.YTD = ytd
+}
+
+// Record stores emp's computed pay for its pay month.
+func (s *Store) Record(emp *model.Employee) error {
+	entry, err := NewEntry(emp)
+	if err != nil {
+		return err
+	}
+	key := Key(emp)
+	entries := s.Employees[key]
+	for i := range entries {
+		if entries[i].Period == entry.Period {
+			entries[i] = entry
+			return nil
+		}
+	}
+	s.Employees[key] = append(entries, entry)
+	return nil
+}
+
+// NewEntry summarises emp's computed pay for its pay month.
+func NewEntry(emp *model.Employee) (Entry, error) {
+	month, ok := emp.PeriodStart()
+	if !ok {
+		return Entry{}, fmt.Errorf("cannot record history: unknown pay month %q %q", emp.Month, emp.Year)
+	}
+	entry := Entry{
+		Period:          Period(month),
+		Earnings:        make(map[string]money.Amount),
+		Deductions:      make(map[string]money.Amount),
+		GrossEarnings:   emp.GrossEarnings,
+		TotalDeductions: emp.TotalDeductions,
+		NetPay:          emp.NetPay,
+	}
+	for _, c := range emp.Earnings() {
+		entry.Earnings[c.Name] += c.Amount
+	}
+	for _, c := range emp.Deductions() {
+		entry.Deductions[c.Name] += c.Amount
+	}
+	return entry, nil
+}
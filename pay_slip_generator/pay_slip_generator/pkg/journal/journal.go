@@ -0,0 +1,111 @@
+// Package journal records payslip deliveries in an append-only file so that
+// an interrupted or failed send run can be repeated without mailing anyone
+// twice.
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"pay_slip_generator/pkg/history"
+	"pay_slip_generator/pkg/model"
+	"strings"
+	"time"
+)
+
+// Delivery statuses.
+const (
+	Delivered = "delivered"
+	Failed    = "failed"
+)
+
+// Entry is one delivery attempt.
+type Entry struct {
+	Key      string    `json:"key"`
+	Employee string    `json:"employee"`
+	Email    string    `json:"email"`
+	Month    string    `json:"month"`
+	Year     string    `json:"year"`
+	PDFHash  string    `json:"pdf_hash"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// Journal is a JSON Lines file of delivery attempts. The latest attempt for
+// a key decides its status.
+type Journal struct {
+	f      *os.File
+	status map[string]string
+}
+
+// Open reads the journal at path, creating it if needed, and keeps it open
+// for appending. A partial last line, left by a crash mid-write, is dropped.
+func Open(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if i := bytes.LastIndexByte(data, '\n'); i+1 < len(data) {
+		data = data[:i+1]
+		if err := os.Truncate(path, int64(len(data))); err != nil {
+			return nil, err
+		}
+	}
+
+	j := &Journal{status: make(map[string]string)}
+	for n, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("journal %s line %d: %w", path, n+1, err)
+		}
+		j.status[e.Key] = e.Status
+	}
+
+	if j.f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	return j.f.Close()
+}
+
+// Key identifies one payslip delivery: the employee (by history.Key), the
+// pay month and the exact PDF sent (by ledger.FileHash), so that a
+// corrected payslip is delivered again.
+func Key(emp *model.Employee, pdfHash string) string {
+	return strings.Join([]string{history.Key(emp), emp.Month, emp.Year, pdfHash}, "|")
+}
+
+// Delivered reports whether the latest attempt for key succeeded.
+func (j *Journal) Delivered(key string) bool {
+	return j.status[key] == Delivered
+}
+
+// Record appends an attempt and flushes it to disk before returning, so the
+// journal survives a crash right after a send.
+func (j *Journal) Record(e Entry) error {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := j.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := j.f.Sync(); err != nil {
+		return err
+	}
+	j.status[e.Key] = e.Status
+	return nil
+}
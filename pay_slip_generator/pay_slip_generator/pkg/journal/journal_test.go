@@ -0,0 +1,116 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pay_slip_generator/pkg/model"
+)
+
+func TestKey(t *testing.T) {
+	emp := &model.Employee{Name: "Asha Rao", Email: "Asha@a.example", Month: "May", Year: "2024"}
+	if got, want := Key(emp, "abc"), "asha@a.example|May|2024|abc"; got != want {
+		t.Errorf("Key = %q, want %q", got, want)
+	}
+	if Key(emp, "abc") == Key(emp, "def") {
+		t.Error("a corrected payslip has the same key")
+	}
+}
+
+func TestRerun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []Entry{
+		{Key: "a", Status: Delivered},
+		{Key: "b", Status: Failed, Error: "421 try later"},
+		{Key: "c", Status: Failed, Error: "421 try later"},
+		{Key: "c", Status: Delivered},
+		{Key: "d", Status: Delivered},
+		{Key: "d", Status: Failed, Error: "550 no such user"},
+	} {
+		if err := j.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next run reads back the latest attempt of each key.
+	j, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"b", false}, // failed: sent again
+		{"c", true},  // failed, then delivered
+		{"d", false}, // latest attempt wins
+		{"e", false}, // never tried
+	}
+	for _, tt := range tests {
+		if got := j.Delivered(tt.key); got != tt.want {
+			t.Errorf("Delivered(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string // file content after Open
+		wantErr bool
+	}{
+		{"missing", "", "", false},
+		{"complete", `{"key":"a","status":"delivered"}` + "\n", `{"key":"a","status":"delivered"}` + "\n", false},
+		{"torn last line", `{"key":"a","status":"delivered"}` + "\n" + `{"key":"b","sta`, `{"key":"a","status":"delivered"}` + "\n", false},
+		{"corrupt line", `{"key":"a"` + "\n" + `{"key":"b","status":"delivered"}` + "\n", "", true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "journal.jsonl")
+		if tt.data != "" {
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		j, err := Open(path)
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "line 1") {
+				t.Errorf("%s: Open error = %v, want line 1", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		// A new entry after a torn line starts on its own line.
+		if err := j.Record(Entry{Key: "c", Status: Delivered}); err != nil {
+			t.Fatal(err)
+		}
+		j.Close()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), tt.want) {
+			t.Errorf("%s: journal = %q, want prefix %q", tt.name, data, tt.want)
+		}
+		if j, err = Open(path); err != nil {
+			t.Fatalf("%s: reopen: %v", tt.name, err)
+		}
+		if !j.Delivered("c") || j.Delivered("b") {
+			t.Errorf("%s: after reopen c delivered %v, b delivered %v", tt.name, j.Delivered("c"), j.Delivered("b"))
+		}
+		j.Close()
+	}
+}
@@ -0,0 +1,261 @@
+// Package ledger is the embedded SQLite record of every payroll run: the
+// input it read, the computed pay of every employee, the payslip PDF issued
+// and what happened to its delivery. It uses a pure-Go SQLite driver, so the
+// binaries still build without cgo.
+package ledger
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"pay_slip_generator/pkg/history"
+	"pay_slip_generator/pkg/model"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Delivery statuses.
+const (
+	Sent    = "sent"
+	Failed  = "failed"
+	Skipped = "skipped" // no email address, already delivered, ...
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY,
+	month       TEXT NOT NULL,
+	year        TEXT NOT NULL,
+	input_path  TEXT NOT NULL,
+	input_hash  TEXT NOT NULL,
+	operator    TEXT NOT NULL,
+	started_at  TEXT NOT NULL,
+	finished_at TEXT
+);
+CREATE TABLE IF NOT EXISTS payslips (
+	id           INTEGER PRIMARY KEY,
+	run_id       INTEGER NOT NULL REFERENCES runs(id),
+	employee_key TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	email        TEXT NOT NULL,
+	period       TEXT NOT NULL,
+	gross        INTEGER NOT NULL,
+	deductions   INTEGER NOT NULL,
+	net_pay      INTEGER NOT NULL,
+	snapshot     TEXT NOT NULL,
+	pdf_path     TEXT NOT NULL,
+	pdf_hash     TEXT NOT NULL,
+	created_at   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS payslips_employee ON payslips(employee_key, period);
+CREATE TABLE IF NOT EXISTS deliveries (
+	id           INTEGER PRIMARY KEY,
+	payslip_id   INTEGER NOT NULL REFERENCES payslips(id),
+	status       TEXT NOT NULL,
+	recipient    TEXT NOT NULL,
+	error        TEXT NOT NULL,
+	attempted_at TEXT NOT NULL
+);
+`
+
+// Ledger is an open ledger database.
+type Ledger struct {
+	db *sql.DB
+}
+
+// Open opens (creating if needed) the ledger database at path.
+func Open(path string) (*Ledger, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ledger %s: %w", path, err)
+	}
+	return &Ledger{db: db}, nil
+}
+
+// Close closes the database.
+func (l *Ledger) Close() error {
+	return l.db.Close()
+}
+
+// Run is one invocation of the generator over an input file.
+type Run struct {
+	ID        int64
+	Month     string
+	Year      string
+	InputPath string
+	InputHash string
+	Operator  string
+	StartedAt time.Time
+}
+
+// StartRun records the start of a run over inputPath for a pay month.
+func (l *Ledger) StartRun(inputPath, month, year, operator string) (*Run, error) {
+	hash, err := FileHash(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	run := &Run{Month: month, Year: year, InputPath: inputPath, InputHash: hash, Operator: operator, StartedAt: time.Now()}
+	res, err := l.db.Exec(`INSERT INTO runs (month, year, input_path, input_hash, operator, started_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		month, year, inputPath, hash, operator, run.StartedAt.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("record run: %w", err)
+	}
+	if run.ID, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// FinishRun records the end of run.
+func (l *Ledger) FinishRun(run *Run) error {
+	_, err := l.db.Exec(`UPDATE runs SET finished_at = ? WHERE id = ?`, time.Now().Format(time.RFC3339), run.ID)
+	return err
+}
+
+// RecordPayslip stores emp's computed pay and the checksum of its payslip at
+// pdfPath, and returns the payslip's id for RecordDelivery.
+func (l *Ledger) RecordPayslip(run *Run, emp *model.Employee, pdfPath string) (int64, error) {
+	month, ok := emp.PeriodStart()
+	if !ok {
+		return 0, fmt.Errorf("cannot record payslip: unknown pay month %q %q", emp.Month, emp.Year)
+	}
+	snapshot, err := json.Marshal(emp)
+	if err != nil {
+		return 0, err
+	}
+	hash, err := FileHash(pdfPath)
+	if err != nil {
+		return 0, err
+	}
+	res, err := l.db.Exec(`INSERT INTO payslips (run_id, employee_key, name, email, period, gross, deductions, net_pay, snapshot, pdf_path, pdf_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.ID, history.Key(emp), emp.Name, emp.Email, history.Period(month),
+		int64(emp.GrossEarnings), int64(emp.TotalDeductions), int64(emp.NetPay),
+		string(snapshot), pdfPath, hash, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return 0, fmt.Errorf("record payslip of %s: %w", emp.Name, err)
+	}
+	return res.LastInsertId()
+}
+
+// RecordDelivery stores one delivery attempt of a payslip; errMsg is empty
+// unless it failed or was skipped, and then says why. Attempts are only ever
+// added, so a payslip that failed and was sent later has both.
+func (l *Ledger) RecordDelivery(payslipID int64, status, recipient, errMsg string) error {
+	switch status {
+	case Sent, Failed, Skipped:
+	default:
+		return fmt.Errorf("record delivery of payslip %d: unknown status %q", payslipID, status)
+	}
+	_, err := l.db.Exec(`INSERT INTO deliveries (payslip_id, status, recipient, error, attempted_at) VALUES (?, ?, ?, ?, ?)`,
+		payslipID, status, recipient, errMsg, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// Delivery is one recorded delivery attempt.
+type Delivery struct {
+	Status      string
+	Recipient   string
+	Error       string
+	AttemptedAt time.Time
+}
+
+// Deliveries returns the delivery attempts of a payslip, oldest first; the
+// last one is its current status.
+func (l *Ledger) Deliveries(payslipID int64) ([]Delivery, error) {
+	rows, err := l.db.Query(`SELECT status, recipient, error, attempted_at FROM deliveries WHERE payslip_id = ? ORDER BY id`, payslipID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var deliveries []Delivery
+	for rows.Next() {
+		var d Delivery
+		var at string
+		if err := rows.Scan(&d.Status, &d.Recipient, &d.Error, &at); err != nil {
+			return nil, err
+		}
+		if d.AttemptedAt, err = time.Parse(time.RFC3339, at); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Payslip returns the latest issued payslip of the employee with the given
+// history.Key for a pay period ("2025-10"): the computed pay and the PDF
+// path, for reprints. It returns sql.ErrNoRows when there is none.
+func (l *Ledger) Payslip(key, period string) (*model.Employee, string, error) {
+	var snapshot, pdfPath string
+	err := l.db.QueryRow(`SELECT snapshot, pdf_path FROM payslips WHERE employee_key = ? AND period = ? ORDER BY id DESC LIMIT 1`,
+		key, period).Scan(&snapshot, &pdfPath)
+	if err != nil {
+		return nil, "", err
+	}
+	var emp model.Employee
+	if err := json.Unmarshal([]byte(snapshot), &emp); err != nil {
+		return nil, "", fmt.Errorf("payslip %s %s: %w", key, period, err)
+	}
+	return &emp, pdfPath, nil
+}
+
+// Apply sets emp.YTD from the latest payslip of every earlier month of the
+// financial year in the ledger, like history.Store.Apply.
+func (l *Ledger) Apply(emp *model.Employee) error {
+	rows, err := l.db.Query(`SELECT period, snapshot FROM payslips WHERE employee_key = ? ORDER BY id`, history.Key(emp))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	latest := make(map[string]history.Entry)
+	for rows.Next() {
+		var period, snapshot string
+		if err := rows.Scan(&period, &snapshot); err != nil {
+			return err
+		}
+		var past model.Employee
+		if err := json.Unmarshal([]byte(snapshot), &past); err != nil {
+			return fmt.Errorf("payslip %s %s: %w", history.Key(emp), period, err)
+		}
+		entry, err := history.NewEntry(&past)
+		if err != nil {
+			return err
+		}
+		latest[period] = entry
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	entries := make([]history.Entry, 0, len(latest))
+	for _, e := range latest {
+		entries = append(entries, e)
+	}
+	history.ApplyEntries(emp, entries)
+	return nil
+}
+
+// FileHash returns the hex SHA-256 of the file at path.
+func FileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
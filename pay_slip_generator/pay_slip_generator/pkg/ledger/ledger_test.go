@@ -0,0 +1,208 @@
+package ledger
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"pay_slip_generator/pkg/history"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+)
+
+// writeFile writes data to name in dir and returns its path.
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openTemp(t *testing.T) (*Ledger, string) {
+	t.Helper()
+	dir := t.TempDir()
+	l, err := Open(filepath.Join(dir, "ledger.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l, dir
+}
+
+func employee(month string, net int64) model.Employee {
+	return model.Employee{
+		Name: "Asha Rao", Email: "Asha@a.example", Month: month, Year: "2024",
+		BasicPayAmount: money.Rupees(net), GrossEarnings: money.Rupees(net), NetPay: money.Rupees(net),
+	}
+}
+
+func TestStartRun(t *testing.T) {
+	l, dir := openTemp(t)
+	input := writeFile(t, dir, "employees.csv", "name,email\n")
+	run, err := l.StartRun(input, "May", "2024", "hr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := FileHash(input)
+	if run.ID == 0 || run.InputHash != hash || run.Operator != "hr" {
+		t.Errorf("StartRun = %+v, want an id and input hash %s", run, hash)
+	}
+	if err := l.FinishRun(run); err != nil {
+		t.Fatal(err)
+	}
+	var finished sql.NullString
+	if err := l.db.QueryRow(`SELECT finished_at FROM runs WHERE id = ?`, run.ID).Scan(&finished); err != nil || !finished.Valid {
+		t.Errorf("finished_at = %v, %v; want set", finished, err)
+	}
+
+	if _, err := l.StartRun(filepath.Join(dir, "missing.csv"), "May", "2024", "hr"); err == nil {
+		t.Error("StartRun with a missing input: want error")
+	}
+}
+
+func TestRecordPayslip(t *testing.T) {
+	l, dir := openTemp(t)
+	run, err := l.StartRun(writeFile(t, dir, "in.csv", "x"), "May", "2024", "hr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		emp     model.Employee
+		pdf     string
+		wantErr bool
+	}{
+		{"first issue", employee("May", 50000), "v1", false},
+		{"corrected reissue", employee("May", 52000), "v2", false},
+		{"unknown month", employee("Maybe", 50000), "v3", true},
+	}
+	for _, tt := range tests {
+		pdfPath := writeFile(t, dir, tt.name+".pdf", tt.pdf)
+		id, err := l.RecordPayslip(run, &tt.emp, pdfPath)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error", tt.name)
+			}
+			continue
+		}
+		if err != nil || id == 0 {
+			t.Fatalf("%s: RecordPayslip = %d, %v", tt.name, id, err)
+		}
+
+		var hash string
+		var net int64
+		if err := l.db.QueryRow(`SELECT pdf_hash, net_pay FROM payslips WHERE id = ?`, id).Scan(&hash, &net); err != nil {
+			t.Fatal(err)
+		}
+		if want, _ := FileHash(pdfPath); hash != want {
+			t.Errorf("%s: pdf_hash = %s, want %s", tt.name, hash, want)
+		}
+		if money.Amount(net) != tt.emp.NetPay {
+			t.Errorf("%s: net_pay = %d, want %d", tt.name, net, tt.emp.NetPay)
+		}
+
+		// Reprints get the latest snapshot.
+		got, path, err := l.Payslip(history.Key(&tt.emp), "2024-05")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != pdfPath || got.NetPay != tt.emp.NetPay || got.Name != tt.emp.Name {
+			t.Errorf("%s: Payslip = %s net %s, want %s net %s", tt.name, path, got.NetPay, pdfPath, tt.emp.NetPay)
+		}
+	}
+
+	if _, _, err := l.Payslip("nobody@a.example", "2024-05"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Payslip of nobody: %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestRecordDelivery(t *testing.T) {
+	l, dir := openTemp(t)
+	run, err := l.StartRun(writeFile(t, dir, "in.csv", "x"), "May", "2024", "hr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	emp := employee("May", 50000)
+	id, err := l.RecordPayslip(run, &emp, writeFile(t, dir, "a.pdf", "pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		status, detail string
+		wantErr        bool
+		want           []string // statuses recorded so far
+	}{
+		{Failed, "421 try later", false, []string{Failed}},
+		{Failed, "421 try later", false, []string{Failed, Failed}},
+		{Sent, "", false, []string{Failed, Failed, Sent}},
+		{Skipped, "already delivered", false, []string{Failed, Failed, Sent, Skipped}},
+		{"bounced", "", true, []string{Failed, Failed, Sent, Skipped}},
+	}
+	for _, tt := range tests {
+		err := l.RecordDelivery(id, tt.status, emp.Email, tt.detail)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("RecordDelivery(%s) error = %v, want error %v", tt.status, err, tt.wantErr)
+		}
+		deliveries, err := l.Deliveries(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, d := range deliveries {
+			got = append(got, d.Status)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("after %s: statuses %v, want %v", tt.status, got, tt.want)
+		}
+		if last := deliveries[len(deliveries)-1]; last.Recipient != emp.Email || last.AttemptedAt.IsZero() {
+			t.Errorf("after %s: last delivery %+v", tt.status, last)
+		}
+	}
+
+	if err := l.RecordDelivery(id+100, Sent, emp.Email, ""); err == nil {
+		t.Error("RecordDelivery of an unknown payslip: want error")
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ledger.db")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := l.StartRun(writeFile(t, dir, "in.csv", "x"), "April", "2024", "hr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	april := employee("April", 50000)
+	if _, err := l.RecordPayslip(run, &april, writeFile(t, dir, "april.pdf", "pdf")); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if _, _, err := l.Payslip(history.Key(&april), "2024-04"); err != nil {
+		t.Errorf("Payslip after reopening: %v", err)
+	}
+	may := employee("May", 50000)
+	if err := l.Apply(&may); err != nil {
+		t.Fatal(err)
+	}
+	if may.YTD.Gross != april.GrossEarnings {
+		t.Errorf("YTD gross after reopening = %s, want %s", may.YTD.Gross, april.GrossEarnings)
+	}
+}
@@ -0,0 +1,135 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._@-]+`)
+
+// sequence makes file names unique within a process.
+var sequence atomic.Int64
+
+// EMLMailer writes every message as a .eml file into a directory, for
+// review or for handing to another mail system.
+type EMLMailer struct {
+	dir string
+}
+
+// NewEML creates dir if needed.
+func NewEML(dir string) (*EMLMailer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &EMLMailer{dir: dir}, nil
+}
+
+// Send writes msg to <dir>/<time>_<n>_<to>.eml.
+func (e *EMLMailer) Send(msg *Message) error {
+	name := fmt.Sprintf("%s_%03d_%s.eml", time.Now().Format("20060102T150405"), sequence.Add(1), unsafeName.ReplaceAllString(msg.To, "_"))
+	var buf bytes.Buffer
+	if _, err := msg.WriteTo(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(e.dir, name), buf.Bytes(), 0644)
+}
+
+// Close does nothing.
+func (e *EMLMailer) Close() error { return nil }
+
+// MboxMailer appends messages to an mbox file (mboxrd quoting).
+type MboxMailer struct {
+	f *os.File
+}
+
+// NewMbox opens path for appending, creating it if needed.
+func NewMbox(path string) (*MboxMailer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &MboxMailer{f: f}, nil
+}
+
+var fromLine = regexp.MustCompile(`^>*From `)
+
+// Send appends msg, quoting body lines that start with "From ".
+func (m *MboxMailer) Send(msg *Message) error {
+	var raw bytes.Buffer
+	if _, err := msg.WriteTo(&raw); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "From %s %s\n", msg.From, time.Now().UTC().Format(time.ANSIC))
+	sc := bufio.NewScanner(&raw)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if fromLine.MatchString(line) {
+			out.WriteByte('>')
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+
+	if _, err := m.f.Write(out.Bytes()); err != nil {
+		return err
+	}
+	return m.f.Sync()
+}
+
+// Close closes the mbox file.
+func (m *MboxMailer) Close() error { return m.f.Close() }
+
+// MaildirMailer delivers messages into a Maildir (tmp, new, cur).
+type MaildirMailer struct {
+	dir  string
+	host string
+}
+
+// NewMaildir creates the Maildir at dir if needed.
+func NewMaildir(dir string) (*MaildirMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return &MaildirMailer{dir: dir, host: strings.NewReplacer("/", "_", ":", "_").Replace(host)}, nil
+}
+
+// Send writes msg to tmp and moves it into new, as Maildir requires.
+func (m *MaildirMailer) Send(msg *Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s", now.Unix(), now.Nanosecond()/1000, os.Getpid(), sequence.Add(1), m.host)
+	var buf bytes.Buffer
+	if _, err := msg.WriteTo(&buf); err != nil {
+		return err
+	}
+	tmp := filepath.Join(m.dir, "tmp", name)
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(m.dir, "new", name))
+}
+
+// Close does nothing.
+func (m *MaildirMailer) Close() error { return nil }
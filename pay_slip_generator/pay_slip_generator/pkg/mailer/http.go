@@ -0,0 +1,124 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HTTPMailer posts messages as JSON to a mail API endpoint:
+//
+//	{"from": {"name": "HR Team", "email": "hr@example.com"},
+//	 "to": "asha@example.com", "subject": "...", "text": "...", "html": "...",
+//	 "inline": [{"filename": "logo.png", "content": "<base64>"}],
+//	 "attachments": [{"filename": "payslip.pdf", "content": "<base64>"}]}
+//
+// Inline files are referenced from the HTML as cid:<filename>.
+//
+// Any 2xx response is success. Pointing the URL at a local stub server
+// makes runs testable without a real provider.
+type HTTPMailer struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTP returns a mailer posting to url with token as bearer token (if
+// not empty).
+func NewHTTP(url, token string) *HTTPMailer {
+	return &HTTPMailer{url: url, token: token, client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+type httpAddress struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email"`
+}
+
+type httpAttachment struct {
+	Filename string `json:"filename"`
+	Content  []byte `json:"content"` // base64 in JSON
+}
+
+type httpMessage struct {
+	From        httpAddress      `json:"from"`
+	To          string           `json:"to"`
+	Subject     string           `json:"subject"`
+	Text        string           `json:"text"`
+	HTML        string           `json:"html,omitempty"`
+	Inline      []httpAttachment `json:"inline,omitempty"`
+	Attachments []httpAttachment `json:"attachments,omitempty"`
+}
+
+// Send posts msg to the endpoint.
+func (h *HTTPMailer) Send(msg *Message) error {
+	body := httpMessage{
+		From:    httpAddress{Name: msg.FromName, Email: msg.From},
+		To:      msg.To,
+		Subject: msg.Subject,
+		Text:    msg.Text,
+		HTML:    msg.HTML,
+	}
+	var err error
+	if body.Inline, err = readAttachments(msg.Inline); err != nil {
+		return err
+	}
+	if body.Attachments, err = readAttachments(msg.Attachments); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &HTTPError{URL: h.url, StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(detail))}
+	}
+	return nil
+}
+
+func readAttachments(paths []string) ([]httpAttachment, error) {
+	var files []httpAttachment
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, httpAttachment{Filename: filepath.Base(path), Content: data})
+	}
+	return files, nil
+}
+
+// HTTPError is a non-2xx reply from the mail API.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("mail API %s: %s: %s", e.URL, e.Status, e.Body)
+}
+
+// Close does nothing.
+func (h *HTTPMailer) Close() error { return nil }
@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter spaces out sends evenly to stay within a provider's quota. It is
+// safe for concurrent use, so several connections can share one.
+type Limiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+// NewLimiter allows perMinute messages a minute; perMinute < 1 means no
+// limit and returns nil, which is a valid Limiter that never waits.
+func NewLimiter(perMinute int) *Limiter {
+	if perMinute < 1 {
+		return nil
+	}
+	return &Limiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// Wait blocks until the next message may be sent.
+func (l *Limiter) Wait() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+	time.Sleep(time.Until(at))
+}
+
+type limited struct {
+	Mailer
+	limiter *Limiter
+}
+
+// WithLimit returns m with every Send waiting for limiter first.
+func WithLimit(m Mailer, limiter *Limiter) Mailer {
+	if limiter == nil {
+		return m
+	}
+	return limited{Mailer: m, limiter: limiter}
+}
+
+func (l limited) Send(msg *Message) error {
+	l.limiter.Wait()
+	return l.Mailer.Send(msg)
+}
@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	// 6000 a minute is one every 10ms.
+	l := NewLimiter(6000)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 2 {
+				l.Wait()
+			}
+		}()
+	}
+	wg.Wait()
+	// Six sends shared by three connections: the first goes at once, the
+	// sixth 50ms later.
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("6 sends took %v, want about 50ms", elapsed)
+	}
+
+	// An idle limiter does not save up sends for a burst.
+	time.Sleep(30 * time.Millisecond)
+	start = time.Now()
+	l.Wait()
+	l.Wait()
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("2 sends after idling took %v, want at least 10ms", elapsed)
+	}
+}
+
+func TestNoLimit(t *testing.T) {
+	l := NewLimiter(0)
+	if l != nil {
+		t.Fatalf("NewLimiter(0) = %+v, want nil", l)
+	}
+	start := time.Now()
+	for range 1000 {
+		l.Wait()
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("nil limiter waited %v", elapsed)
+	}
+
+	m := &fakeMailer{}
+	if WithLimit(m, nil) != Mailer(m) {
+		t.Error("WithLimit(m, nil) wraps m")
+	}
+}
+
+func TestWithLimit(t *testing.T) {
+	m := &fakeMailer{}
+	limited := WithLimit(m, NewLimiter(6000))
+	start := time.Now()
+	for range 4 {
+		if err := limited.Send(&Message{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("4 limited sends took %v, want at least 30ms", elapsed)
+	}
+	if m.sends != 4 {
+		t.Errorf("%d sends reached the mailer, want 4", m.sends)
+	}
+}
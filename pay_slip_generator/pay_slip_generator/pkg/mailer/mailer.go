@@ -0,0 +1,149 @@
+// Package mailer sends payslip emails through a pluggable transport: an
+// SMTP server, files on disk (.eml, mbox or Maildir) or an HTTP mail API.
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Message is one email with file attachments. With HTML set it is sent as
+// multipart/alternative, Text being the plain-text alternative.
+type Message struct {
+	FromName    string
+	From        string
+	To          string
+	Subject     string
+	Text        string
+	HTML        string
+	Inline      []string // file paths, referenced from HTML as cid:<file name>
+	Attachments []string // file paths
+}
+
+// Mailer delivers messages. Implementations are not safe for concurrent use.
+type Mailer interface {
+	Send(msg *Message) error
+	Close() error
+}
+
+// Transports accepted by Config.Transport.
+const (
+	SMTP    = "smtp"
+	EML     = "eml"
+	Mbox    = "mbox"
+	Maildir = "maildir"
+	HTTP    = "http"
+)
+
+// Config selects and configures a transport.
+type Config struct {
+	Transport string
+
+	// SMTP
+	Host     string
+	Port     int
+	Username string
+	Password string
+
+	// Dir is the output directory of eml and maildir, or the file of mbox.
+	Dir string
+
+	// HTTP API: messages are POSTed as JSON to URL, with Token as a bearer
+	// token when set.
+	URL   string
+	Token string
+}
+
+// ConfigFromEnv reads the transport configuration from the environment:
+// MAIL_TRANSPORT (default smtp), SMTP_HOST, SMTP_PORT, SMTP_EMAIL,
+// SMTP_PASSWORD, MAIL_DIR, MAIL_HTTP_URL and MAIL_HTTP_TOKEN.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Transport: strings.ToLower(os.Getenv("MAIL_TRANSPORT")),
+		Host:      os.Getenv("SMTP_HOST"),
+		Port:      587,
+		Username:  os.Getenv("SMTP_EMAIL"),
+		Password:  os.Getenv("SMTP_PASSWORD"),
+		Dir:       os.Getenv("MAIL_DIR"),
+		URL:       os.Getenv("MAIL_HTTP_URL"),
+		Token:     os.Getenv("MAIL_HTTP_TOKEN"),
+	}
+	if cfg.Transport == "" {
+		cfg.Transport = SMTP
+	}
+	if p := os.Getenv("SMTP_PORT"); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SMTP_PORT=%q: %v", p, err)
+		}
+		cfg.Port = port
+	}
+	return cfg, nil
+}
+
+// New returns the transport selected by cfg. The SMTP transport connects
+// right away so that bad settings are reported before anything is sent.
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Transport {
+	case SMTP:
+		if cfg.Host == "" || cfg.Username == "" || cfg.Password == "" {
+			return nil, fmt.Errorf("smtp transport needs SMTP_HOST, SMTP_PORT, SMTP_EMAIL and SMTP_PASSWORD")
+		}
+		return NewSMTP(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	case EML:
+		return NewEML(cfg.dir("mail"))
+	case Mbox:
+		return NewMbox(cfg.dir("payslips.mbox"))
+	case Maildir:
+		return NewMaildir(cfg.dir("Maildir"))
+	case HTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("http transport needs MAIL_HTTP_URL")
+		}
+		return NewHTTP(cfg.URL, cfg.Token), nil
+	}
+	return nil, fmt.Errorf("unknown mail transport %q (want smtp, eml, mbox, maildir or http)", cfg.Transport)
+}
+
+func (cfg Config) dir(def string) string {
+	if cfg.Dir != "" {
+		return cfg.Dir
+	}
+	return def
+}
+
+// gomail builds the MIME message.
+func (m *Message) gomail() *gomail.Message {
+	g := gomail.NewMessage()
+	g.SetAddressHeader("From", m.From, m.FromName)
+	g.SetHeader("To", m.To)
+	g.SetHeader("Subject", m.Subject)
+	g.SetBody("text/plain", m.Text)
+	if m.HTML != "" {
+		g.AddAlternative("text/html", m.HTML)
+	}
+	for _, path := range m.Inline {
+		g.Embed(path)
+	}
+	for _, path := range m.Attachments {
+		g.Attach(path)
+	}
+	return g
+}
+
+// ContentID returns the cid: URL under which the inline file path can be
+// referenced from the HTML body.
+func ContentID(path string) string {
+	return "cid:" + filepath.Base(path)
+}
+
+// WriteTo writes m as an RFC 5322 message.
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	return m.gomail().WriteTo(w)
+}
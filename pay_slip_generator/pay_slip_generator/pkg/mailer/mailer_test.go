@@ -0,0 +1,194 @@
+package mailer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testMessage returns a message with a PDF attachment and a body line that
+// mbox has to quote.
+func testMessage(t *testing.T, to string) *Message {
+	t.Helper()
+	pdf := filepath.Join(t.TempDir(), "payslip.pdf")
+	if err := os.WriteFile(pdf, []byte("%PDF-1.3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &Message{
+		FromName: "HR Team", From: "hr@a.example", To: to,
+		Subject:     "Payslip for May 2024",
+		Text:        "Hi,\nFrom now on payslips come by email.\n",
+		Attachments: []string{pdf},
+	}
+}
+
+// checkMessage parses an RFC 5322 message and checks its headers.
+func checkMessage(t *testing.T, r io.Reader, to string) {
+	t.Helper()
+	m, err := mail.ReadMessage(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Header.Get("To"); got != to {
+		t.Errorf("To = %q, want %q", got, to)
+	}
+	if got := m.Header.Get("Subject"); got != "Payslip for May 2024" {
+		t.Errorf("Subject = %q", got)
+	}
+	if ct := m.Header.Get("Content-Type"); !strings.HasPrefix(ct, "multipart/mixed") {
+		t.Errorf("Content-Type = %q, want multipart/mixed with the PDF", ct)
+	}
+}
+
+func TestEML(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := New(Config{Transport: EML, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	for _, to := range []string{"asha@a.example", "ravi@a.example"} {
+		if err := m.Send(testMessage(t, to)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("eml files = %v, %v; want 2", files, err)
+	}
+	for _, path := range files {
+		// <time>_<n>_<to>.eml
+		parts := strings.SplitN(strings.TrimSuffix(filepath.Base(path), ".eml"), "_", 3)
+		if len(parts) != 3 {
+			t.Fatalf("file name %s", path)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkMessage(t, f, parts[2])
+		f.Close()
+	}
+}
+
+func TestMbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "payslips.mbox")
+	m, err := New(Config{Transport: Mbox, Dir: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, to := range []string{"asha@a.example", "ravi@a.example"} {
+		if err := m.Send(testMessage(t, to)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each message starts with a "From " line; the body line that starts
+	// with "From " is quoted.
+	messages := strings.Split(string(data), "\nFrom hr@a.example ")
+	if len(messages) != 2 || !strings.HasPrefix(messages[0], "From hr@a.example ") {
+		t.Fatalf("mbox has %d messages, want 2:\n%s", len(messages), data)
+	}
+	if !strings.Contains(string(data), "\n>From now on") || strings.Contains(string(data), "\nFrom now on") {
+		t.Error(`body line "From now on" not quoted`)
+	}
+	for i, to := range []string{"asha@a.example", "ravi@a.example"} {
+		_, msg, _ := strings.Cut(messages[i], "\n")
+		checkMessage(t, strings.NewReader(msg), to)
+	}
+}
+
+func TestMaildir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Maildir")
+	m, err := New(Config{Transport: Maildir, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err := m.Send(testMessage(t, "asha@a.example")); err != nil {
+		t.Fatal(err)
+	}
+	for sub, want := range map[string]int{"tmp": 0, "new": 1, "cur": 0} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil || len(entries) != want {
+			t.Errorf("%s has %d messages (%v), want %d", sub, len(entries), err, want)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "new"))
+	if len(entries) == 1 {
+		f, err := os.Open(filepath.Join(dir, "new", entries[0].Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		checkMessage(t, f, "asha@a.example")
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	var got httpMessage
+	status := http.StatusAccepted
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "no token", http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, "queued")
+	}))
+	defer srv.Close()
+
+	m, err := New(Config{Transport: HTTP, URL: srv.URL, Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err := m.Send(testMessage(t, "asha@a.example")); err != nil {
+		t.Fatal(err)
+	}
+	if got.To != "asha@a.example" || got.From.Name != "HR Team" || len(got.Attachments) != 1 ||
+		got.Attachments[0].Filename != "payslip.pdf" || string(got.Attachments[0].Content) != "%PDF-1.3" {
+		t.Errorf("posted %+v", got)
+	}
+
+	status = http.StatusUnprocessableEntity
+	err = m.Send(testMessage(t, "asha@a.example"))
+	var he *HTTPError
+	if !errors.As(err, &he) || he.StatusCode != status || he.Body != "queued" {
+		t.Errorf("Send error = %v, want HTTPError %d", err, status)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		cfg     Config
+		wantErr string
+	}{
+		{Config{Transport: SMTP}, "SMTP_HOST"},
+		{Config{Transport: HTTP}, "MAIL_HTTP_URL"},
+		{Config{Transport: "pigeon"}, "unknown mail transport"},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("New(%s) error = %v, want %q", tt.cfg.Transport, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"net"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Class tells whether a failed send is worth retrying.
+type Class int
+
+const (
+	// Transient failures (4xx replies, network trouble) may succeed later.
+	Transient Class = iota
+	// Permanent failures (5xx replies such as an unknown mailbox, missing
+	// attachments) will fail again the same way.
+	Permanent
+)
+
+func (c Class) String() string {
+	if c == Permanent {
+		return "permanent"
+	}
+	return "transient"
+}
+
+// replyCode finds an SMTP reply code at the start of an error message, for
+// errors that were wrapped as text.
+var replyCode = regexp.MustCompile(`^(?:.*: )?([2-5][0-9][0-9])[ -]`)
+
+// Classify sorts a send error into Transient or Permanent. SMTP replies go
+// by their code (5xx permanent, 4xx transient), HTTP API replies by status
+// (429 and 5xx transient, other 4xx permanent). Network errors are transient;
+// so is anything unrecognised, since a retry costs little.
+func Classify(err error) Class {
+	var tp *textproto.Error
+	if errors.As(err, &tp) {
+		return classifyCode(tp.Code)
+	}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		if he.StatusCode == 429 || he.StatusCode >= 500 {
+			return Transient
+		}
+		return Permanent
+	}
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return Permanent
+	}
+	var ne net.Error
+	if errors.As(err, &ne) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return Transient
+	}
+	if m := replyCode.FindStringSubmatch(err.Error()); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return classifyCode(code)
+	}
+	return Transient
+}
+
+func classifyCode(code int) Class {
+	if code >= 500 {
+		return Permanent
+	}
+	return Transient
+}
+
+// Attempt is one try at sending a message.
+type Attempt struct {
+	At    time.Time
+	Err   error // nil when the message was sent
+	Class Class // of Err
+	Wait  time.Duration
+}
+
+func (a Attempt) String() string {
+	if a.Err == nil {
+		return a.At.Format(time.TimeOnly) + " sent"
+	}
+	return fmt.Sprintf("%s %s: %v", a.At.Format(time.TimeOnly), a.Class, a.Err)
+}
+
+// RetryPolicy retries transient failures with exponential backoff and
+// jitter: the n-th retry waits a random time between half and all of
+// BaseDelay*2^(n-1), capped at MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetry tries up to 4 times, waiting about 2s, 4s and 8s in between.
+func DefaultRetry() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 4, BaseDelay: 2 * time.Second, MaxDelay: time.Minute}
+}
+
+// Send sends msg with m, retrying transient failures, and returns every
+// attempt made along with the last error.
+func (p RetryPolicy) Send(m Mailer, msg *Message) ([]Attempt, error) {
+	var attempts []Attempt
+	for n := 1; ; n++ {
+		a := Attempt{At: time.Now()}
+		a.Err = m.Send(msg)
+		if a.Err == nil {
+			return append(attempts, a), nil
+		}
+		a.Class = Classify(a.Err)
+		if a.Class == Permanent || n >= p.MaxAttempts {
+			return append(attempts, a), a.Err
+		}
+		a.Wait = p.backoff(n)
+		attempts = append(attempts, a)
+		time.Sleep(a.Wait)
+	}
+}
+
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay << (n - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
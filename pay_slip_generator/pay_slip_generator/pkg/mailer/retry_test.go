@@ -0,0 +1,122 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/textproto"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Class
+	}{
+		{&textproto.Error{Code: 421, Msg: "try again later"}, Transient},
+		{&textproto.Error{Code: 452, Msg: "mailbox full"}, Transient},
+		{&textproto.Error{Code: 550, Msg: "no such user"}, Permanent},
+		{&textproto.Error{Code: 554, Msg: "rejected"}, Permanent},
+		{fmt.Errorf("send: %w", &textproto.Error{Code: 553, Msg: "bad address"}), Permanent},
+		{errors.New("gomail: could not send email 1: 550 5.1.1 unknown user"), Permanent},
+		{errors.New("451 4.7.1 greylisted"), Transient},
+		{&HTTPError{StatusCode: 429}, Transient},
+		{&HTTPError{StatusCode: 503}, Transient},
+		{&HTTPError{StatusCode: 400}, Permanent},
+		{&HTTPError{StatusCode: 401}, Permanent},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, Transient},
+		{io.EOF, Transient},
+		{&fs.PathError{Op: "open", Path: "payslip.pdf", Err: fs.ErrNotExist}, Permanent},
+		{errors.New("something odd"), Transient},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+// fakeMailer fails with errs in turn, then succeeds.
+type fakeMailer struct {
+	errs  []error
+	sends int
+}
+
+func (f *fakeMailer) Send(msg *Message) error {
+	f.sends++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *fakeMailer) Close() error { return nil }
+
+func TestRetryPolicySend(t *testing.T) {
+	busy := &textproto.Error{Code: 421, Msg: "busy"}
+	unknown := &textproto.Error{Code: 550, Msg: "no such user"}
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond}
+	tests := []struct {
+		name    string
+		errs    []error
+		sends   int
+		wantErr error
+	}{
+		{"sent", nil, 1, nil},
+		{"transient then sent", []error{busy, busy}, 3, nil},
+		{"gives up", []error{busy, busy, busy, busy}, 3, busy},
+		{"permanent", []error{unknown}, 1, unknown},
+		{"transient then permanent", []error{busy, unknown}, 2, unknown},
+	}
+	for _, tt := range tests {
+		m := &fakeMailer{errs: tt.errs}
+		attempts, err := policy.Send(m, &Message{})
+		if err != tt.wantErr {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if m.sends != tt.sends || len(attempts) != tt.sends {
+			t.Errorf("%s: %d sends, %d attempts; want %d", tt.name, m.sends, len(attempts), tt.sends)
+		}
+		for i, a := range attempts {
+			last := i == len(attempts)-1
+			if last != (a.Wait == 0) {
+				t.Errorf("%s: attempt %d waited %v", tt.name, i+1, a.Wait)
+			}
+			if !last && a.Class != Transient {
+				t.Errorf("%s: retried a %s failure", tt.name, a.Class)
+			}
+		}
+		if tt.wantErr == nil && attempts[len(attempts)-1].Err != nil {
+			t.Errorf("%s: last attempt %v", tt.name, attempts[len(attempts)-1])
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 2 * time.Second, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		n        int
+		min, max time.Duration
+	}{
+		{1, time.Second, 2 * time.Second},
+		{2, 2 * time.Second, 4 * time.Second},
+		{3, 4 * time.Second, 8 * time.Second},
+		{4, 5 * time.Second, 10 * time.Second},  // capped
+		{70, 5 * time.Second, 10 * time.Second}, // shift overflow
+	}
+	for _, tt := range tests {
+		for range 100 {
+			if d := p.backoff(tt.n); d < tt.min || d > tt.max {
+				t.Fatalf("backoff(%d) = %v, want in [%v, %v]", tt.n, d, tt.min, tt.max)
+			}
+		}
+	}
+	if d := (RetryPolicy{}).backoff(1); d != 0 {
+		t.Errorf("zero policy backoff = %v, want 0", d)
+	}
+}
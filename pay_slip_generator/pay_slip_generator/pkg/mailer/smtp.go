@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"crypto/tls"
+
+	"gopkg.in/gomail.v2"
+)
+
+// SMTPMailer sends over one persistent SMTP connection.
+type SMTPMailer struct {
+	dialer *gomail.Dialer
+	conn   gomail.SendCloser
+}
+
+// NewSMTP connects to an SMTP server. Port 465 uses implicit TLS; other
+// ports upgrade with STARTTLS when the server offers it.
+func NewSMTP(host string, port int, username, password string) (*SMTPMailer, error) {
+	d := gomail.NewDialer(host, port, username, password)
+	// GoDaddy SMTP commonly uses implicit SSL on 465
+	if port == 465 {
+		d.SSL = true
+	}
+	// Helps TLS handshake on many servers
+	d.TLSConfig = &tls.Config{ServerName: host}
+
+	conn, err := d.Dial()
+	if err != nil {
+		return nil, err
+	}
+	return &SMTPMailer{dialer: d, conn: conn}, nil
+}
+
+// Send makes one attempt at sending msg. It connects first when there is
+// no connection, and drops the connection after a failure so that the next
+// attempt starts on a fresh one.
+func (s *SMTPMailer) Send(msg *Message) error {
+	if s.conn == nil {
+		conn, err := s.dialer.Dial()
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	// Talk to the SendCloser directly: gomail.Send flattens errors to text,
+	// which would hide the SMTP reply code from Classify.
+	if err := s.conn.Send(msg.From, []string{msg.To}, msg.gomail()); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection.
+func (s *SMTPMailer) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
@@ -0,0 +1,147 @@
+// Package mailtemplate renders payslip email subjects and bodies from
+// editable templates: subject.txt and body.txt (text/template) and
+// body.html (html/template). Templates see every model.Employee field and
+// method directly ({{.Name}}, {{.NetPay}}, {{.NetPayInWords}}), the run as
+// {{.Run}}, the employee's company profile as {{.Company}}, the inline logo
+// reference as {{.Logo}} and, for encrypted payslips, how the password is
+// made up as {{.PasswordHint}}.
+package mailtemplate
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"pay_slip_generator/pkg/company"
+	"pay_slip_generator/pkg/model"
+)
+
+//go:embed default
+var defaults embed.FS
+
+// Template file names, in the templates directory and in the defaults.
+const (
+	SubjectFile = "subject.txt"
+	TextFile    = "body.txt"
+	HTMLFile    = "body.html"
+)
+
+// Run describes the payroll run an email belongs to.
+type Run struct {
+	FromName string // sender display name, e.g. "HR Team"
+	From     string // sender address
+	Operator string
+	Date     time.Time
+}
+
+// Data is what the templates are executed with.
+type Data struct {
+	*model.Employee
+	Run     Run
+	Company *company.Profile
+	Logo    htmltemplate.URL // "cid:..." of the inline logo, empty without one
+
+	// PasswordHint describes the PDF password without revealing it, e.g.
+	// "the first 4 characters of your PAN in capitals followed by your date
+	// of birth as DDMM"; empty when payslips are not encrypted.
+	PasswordHint string
+}
+
+// Rendered is an email rendered for one employee.
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Templates holds the parsed subject and body templates.
+type Templates struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// Default returns the built-in templates.
+func Default() *Templates {
+	t, err := Load("")
+	if err != nil {
+		panic(err) // the embedded templates are known to parse
+	}
+	return t
+}
+
+// Load parses the templates in dir. Files missing from dir, or all of them
+// when dir is empty, fall back to the built-in defaults.
+func Load(dir string) (*Templates, error) {
+	read := func(name string) (string, string, error) {
+		if dir != "" {
+			path := filepath.Join(dir, name)
+			data, err := os.ReadFile(path)
+			if err == nil {
+				return path, string(data), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", "", err
+			}
+		}
+		data, err := defaults.ReadFile("default/" + name)
+		return "default/" + name, string(data), err
+	}
+
+	t := &Templates{}
+	path, src, err := read(SubjectFile)
+	if err != nil {
+		return nil, err
+	}
+	if t.subject, err = texttemplate.New(SubjectFile).Option("missingkey=error").Parse(src); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if path, src, err = read(TextFile); err != nil {
+		return nil, err
+	}
+	if t.text, err = texttemplate.New(TextFile).Option("missingkey=error").Parse(src); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if path, src, err = read(HTMLFile); err != nil {
+		return nil, err
+	}
+	if t.html, err = htmltemplate.New(HTMLFile).Option("missingkey=error").Parse(src); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Render executes the templates for data. The subject must render to a
+// single line.
+func (t *Templates) Render(data Data) (Rendered, error) {
+	var r Rendered
+	var buf bytes.Buffer
+	if err := t.subject.Execute(&buf, data); err != nil {
+		return r, err
+	}
+	r.Subject = strings.TrimSpace(buf.String())
+	if strings.ContainsAny(r.Subject, "\r\n") {
+		return r, fmt.Errorf("%s: subject spans several lines", SubjectFile)
+	}
+
+	buf.Reset()
+	if err := t.text.Execute(&buf, data); err != nil {
+		return r, err
+	}
+	r.Text = buf.String()
+
+	buf.Reset()
+	if err := t.html.Execute(&buf, data); err != nil {
+		return r, err
+	}
+	r.HTML = buf.String()
+	return r, nil
+}
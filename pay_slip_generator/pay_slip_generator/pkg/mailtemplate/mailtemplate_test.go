@@ -0,0 +1,27 @@
+package mailtemplate
+
+import (
+	"strings"
+	"testing"
+
+	"pay_slip_generator/pkg/company"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+)
+
+func TestDefaultTextParagraphs(t *testing.T) {
+	emp := &model.Employee{Name: "Asha", Month: "Dec", Year: "2024", NetPay: money.Rupees(1000)}
+	for _, hint := range []string{"", "your date of birth as DDMM"} {
+		r, err := Default().Render(Data{Employee: emp, Run: Run{FromName: "HR Team"}, Company: company.Default(), PasswordHint: hint})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "for Dec 2024.\n\nNet pay:"
+		if hint != "" {
+			want = "for Dec 2024.\n\nThe payslip is password protected. The password is " + hint + ".\n\nNet pay:"
+		}
+		if !strings.Contains(r.Text, want) {
+			t.Errorf("hint %q: text body\n%s\nwant it to contain %q", hint, r.Text, want)
+		}
+	}
+}
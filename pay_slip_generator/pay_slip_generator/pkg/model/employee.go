@@ -2,8 +2,9 @@ package model
 
 import (
 	"fmt"
-	"math"
+	"pay_slip_generator/pkg/money"
 	"strings"
+	"time"
 )
 
 // Employee represents a single row of data from the Excel file.
@@ -17,47 +18,201 @@ type Employee struct {
 	Designation string
 	Email       string // Added Email field
 	BankAcNo    string
-	DOJ         string // Date of Joining
+	DOJ         time.Time // Date of Joining
+	DOB         time.Time // Date of birth; only used for PDF passwords
+	ExitDate    time.Time // Last working day; zero while employed
 	Gender      string
 	PAN         string
+	IFSC        string // Bank branch IFSC code
+	State       string // Work location state, for professional tax
+	Entity      string // Legal entity (company profile id) the employee is on the rolls of
 
 	UAN  string // New Field
 	PFNo string // New Field - PF Account Number
 
-	// Attendance
-	StandardDays string
-	PayableDays  string
-	LOPDays      string // Loss of Pay
+	// Provident Fund options, used when PF is computed by payroll.Engine
+	PFOnFullBasic bool    // contribute on full PF wages instead of the wage ceiling
+	VPFPercent    float64 // voluntary PF, % of PF wages
+
+	// Employees' State Insurance
+	ESINo      string // ESI insurance person (IP) number
+	ESICovered bool   // covered earlier in the current contribution period
+
+	// Income tax (TDS) inputs, used when TDS is computed by payroll.Engine.
+	// Declarations are annual amounts.
+	TaxRegime    string       // "old" or "new" (default)
+	Declared80C  money.Amount // 80C investments, excluding employee PF
+	Declared80D  money.Amount // health insurance premium
+	HRAExemption money.Amount
+
+	// Month-by-month HRA exemption, when computed from rent declarations.
+	HRAExemptionMonths []HRAExemptionMonth
+
+	// Totals of the financial year before this pay month
+	YTD YTD
+
+	// Salary structure: when both are set, payroll.Engine derives the monthly
+	// earnings from the annual CTC instead of reading them from the sheet.
+	AnnualCTC       money.Amount
+	SalaryStructure string
+
+	// Attendance, in days (half days allowed). payroll.Engine derives
+	// StandardDays from the proration basis and PayableDays from LOPDays.
+	StandardDays float64
+	PayableDays  float64
+	LOPDays      float64 // Loss of Pay
 
 	// Earnings (Rate and Amount)
-	BasicPayRate   float64
-	BasicPayAmount float64
+	BasicPayRate   money.Amount
+	BasicPayAmount money.Amount
+
+	HRARate   money.Amount
+	HRAAmount money.Amount
 
-	HRARate   float64
-	HRAAmount float64
+	OtherAllowanceRate   money.Amount
+	OtherAllowanceAmount money.Amount
 
-	OtherAllowanceRate   float64
-	OtherAllowanceAmount float64
+	// Additional earnings beyond Basic, HRA and Other Allowance (e.g. Special
+	// Allowance, Conveyance, LTA, Bonus), in sheet order.
+	ExtraEarnings []Component
 
 	// Deductions
-	ProfessionalTax float64
-	PF              float64 // Provident Fund (if any)
-	IncomeTax       float64
+	ProfessionalTax money.Amount
+	PF              money.Amount // Provident Fund (if any)
+	IncomeTax       money.Amount
 	HasIncomeTax    bool
 
+	// Additional deductions (e.g. ESI, Loan EMI, Canteen), in sheet order.
+	ExtraDeductions []Component
+
+	// Employer-side contributions that are part of CTC but not paid out
+	// (e.g. employer PF). They do not affect net pay.
+	EmployerContributions []Component
+
 	// Totals
-	GrossEarnings   float64
-	TotalDeductions float64
-	NetPay          float64
+	GrossEarnings   money.Amount
+	TotalDeductions money.Amount
+	NetPay          money.Amount
+}
+
+// YTD holds year-to-date amounts of the financial year.
+type YTD struct {
+	Gross           money.Amount
+	TotalDeductions money.Amount
+	PF              money.Amount
+	ProfessionalTax money.Amount
+	IncomeTax       money.Amount
+
+	// Components are the totals by earning and deduction name, when known
+	// from the pay history; payslips then show a YTD column.
+	Components map[string]money.Amount
+}
+
+// YTDAmount returns the year-to-date total of c including this month.
+func (e *Employee) YTDAmount(c Component) money.Amount {
+	return e.YTD.Components[c.Name] + c.Amount
+}
+
+// HRAExemptionMonth is one month of an HRA exemption computation: the
+// exemption is the least of HRA, RentLessBasic and BasicShare.
+type HRAExemptionMonth struct {
+	Month         time.Time
+	HRA           money.Amount // HRA received
+	Rent          money.Amount // rent paid
+	RentLessBasic money.Amount // rent paid less 10% of basic
+	BasicShare    money.Amount // 50% (metro) or 40% of basic
+	Metro         bool
+	Exempt        money.Amount
+}
+
+// Component is one named earning or deduction line on the payslip.
+type Component struct {
+	Name   string
+	Rate   money.Amount // Standard (full month) rate; 0 when not applicable
+	Amount money.Amount
+}
+
+// Earnings returns every earning line in payslip order: the fixed Basic, HRA
+// and Other Allowance rows followed by ExtraEarnings.
+func (e *Employee) Earnings() []Component {
+	earnings := []Component{
+		{Name: "Basic Pay", Rate: e.BasicPayRate, Amount: e.BasicPayAmount},
+		{Name: "House Rent Allowance", Rate: e.HRARate, Amount: e.HRAAmount},
+		{Name: "Other Allowance", Rate: e.OtherAllowanceRate, Amount: e.OtherAllowanceAmount},
+	}
+	return append(earnings, e.ExtraEarnings...)
+}
+
+// Deductions returns every deduction line in payslip order. Fixed deductions
+// with a zero amount are left out, except Income Tax when the sheet has a
+// tax column.
+func (e *Employee) Deductions() []Component {
+	var deductions []Component
+	if e.ProfessionalTax != 0 {
+		deductions = append(deductions, Component{Name: "Professional Tax", Amount: e.ProfessionalTax})
+	}
+	if e.PF != 0 {
+		deductions = append(deductions, Component{Name: "Provident Fund", Amount: e.PF})
+	}
+	if e.HasIncomeTax {
+		deductions = append(deductions, Component{Name: "Income Tax", Amount: e.IncomeTax})
+	}
+	return append(deductions, e.ExtraDeductions...)
+}
+
+// PeriodStart returns the first day of the pay month, accepting full
+// ("March") or abbreviated ("Mar") month names.
+func (e *Employee) PeriodStart() (time.Time, bool) {
+	return periodStart(e.Month, e.Year)
+}
+
+func periodStart(month, year string) (time.Time, bool) {
+	// Try parsing full month name first (e.g., "March 2024")
+	t, err := time.Parse("January 2006", fmt.Sprintf("%s %s", month, year))
+	if err != nil {
+		// Fallback to abbreviated month name (e.g., "Mar 2024", "Feb 2024")
+		t, err = time.Parse("Jan 2006", fmt.Sprintf("%s %s", month, year))
+		if err != nil {
+			return time.Time{}, false
+		}
+	}
+	return t, true
+}
+
+// DateLayout is how dates are shown on payslips.
+const DateLayout = "2006-01-02"
+
+// FormatDate formats t with DateLayout, or returns "" for the zero time.
+func FormatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(DateLayout)
+}
+
+// DaysInMonth returns the number of calendar days in the given pay month.
+// It falls back to 30 when the month cannot be parsed.
+func DaysInMonth(month, year string) int {
+	t, ok := periodStart(month, year)
+	if !ok {
+		return 30 // Default fallback
+	}
+	// Go to the first day of the next month, then subtract one day to get the last day of the current month
+	return t.AddDate(0, 1, 0).Add(-24 * time.Hour).Day()
 }
 
 // NetPayInWords converts the NetPay to words.
 func (e *Employee) NetPayInWords() string {
-	intPart := int(e.NetPay)
-	fracPart := int(math.Round((e.NetPay - float64(intPart)) * 100))
+	// The sign comes from the amount: Whole() is 0 for -0.50.
+	net, sign := e.NetPay, ""
+	if net < 0 {
+		net, sign = -net, "MINUS "
+	}
+	intPart := int(net.Whole())
+	fracPart := int(net.Paise())
 
 	words := convertNumberToWords(intPart)
-	out := fmt.Sprintf("RUPEES %s", strings.ToUpper(words))
+	out := fmt.Sprintf("RUPEES %s%s", sign, strings.ToUpper(words))
 
 	if fracPart > 0 {
 		paiseWords := convertNumberToWords(fracPart)
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"testing"
+
+	"pay_slip_generator/pkg/money"
+)
+
+func TestNetPayInWords(t *testing.T) {
+	tests := []struct {
+		net  string
+		want string
+	}{
+		{"0", "RUPEES ZERO ONLY"},
+		{"25000.50", "RUPEES TWENTY FIVE THOUSAND AND FIFTY PAISE ONLY"},
+		{"-0.50", "RUPEES MINUS ZERO AND FIFTY PAISE ONLY"},
+		{"-5.05", "RUPEES MINUS FIVE AND FIVE PAISE ONLY"},
+	}
+	for _, tt := range tests {
+		e := Employee{NetPay: money.MustParse(tt.net)}
+		if got := e.NetPayInWords(); got != tt.want {
+			t.Errorf("NetPayInWords(%s) = %q, want %q", tt.net, got, tt.want)
+		}
+	}
+}
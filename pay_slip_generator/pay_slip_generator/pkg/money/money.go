@@ -0,0 +1,183 @@
+// Package money provides an exact fixed-point type for rupee amounts.
+//
+// Amounts are held as a whole number of paise so that sums and differences
+// never drift. Whenever a value has to be rounded (percentages, proration)
+// the rule is round half up, i.e. half away from zero, to the paisa or to the
+// rupee as selected by a Rounding.
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Amount is a sum of money in paise.
+type Amount int64
+
+// Zero is the zero Amount.
+const Zero Amount = 0
+
+// Rupees returns an Amount of r whole rupees.
+func Rupees(r int64) Amount {
+	return Amount(r * 100)
+}
+
+// plainDecimal is what Parse accepts once commas are removed.
+var plainDecimal = regexp.MustCompile(`^-?\d+(\.\d{1,2})?$`)
+
+// Parse reads a decimal rupee amount such as "12,500", "1234.5" or "-10.05".
+// Commas and surrounding spaces are ignored and an empty string is zero.
+// Anything else that is not a plain decimal with at most two decimals, e.g.
+// "1e3", "0x10" or "12/5", is an error.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
+	if s == "" {
+		return 0, nil
+	}
+	if !plainDecimal.MatchString(s) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, big.NewRat(100, 1))
+	return fromRat(r, 1)
+}
+
+// MustParse is like Parse but panics on error. It is meant for constants.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Whole returns the rupee part of a, truncated toward zero.
+func (a Amount) Whole() int64 {
+	return int64(a) / 100
+}
+
+// Paise returns the paise part of a, between 0 and 99.
+func (a Amount) Paise() int64 {
+	p := int64(a) % 100
+	if p < 0 {
+		p = -p
+	}
+	return p
+}
+
+// String formats a with exactly two decimals, e.g. "1234.50" or "-0.05".
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign = "-"
+	}
+	whole := a.Whole()
+	if whole < 0 {
+		whole = -whole
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, whole, a.Paise())
+}
+
+// MulDiv returns a * num / den rounded half up to the paisa, e.g.
+// a.MulDiv(12, 100) for 12% or a.MulDiv(payable, standard) for proration.
+func (a Amount) MulDiv(num, den int64) Amount {
+	if den == 0 {
+		return 0
+	}
+	r := new(big.Rat).SetFrac(big.NewInt(int64(a)), big.NewInt(1))
+	r.Mul(r, new(big.Rat).SetFrac(big.NewInt(num), big.NewInt(den)))
+	out, _ := fromRat(r, 1)
+	return out
+}
+
+// MulRat returns a * r rounded half up to the paisa.
+func (a Amount) MulRat(r *big.Rat) Amount {
+	x := new(big.Rat).SetInt64(int64(a))
+	x.Mul(x, r)
+	out, _ := fromRat(x, 1)
+	return out
+}
+
+// Percent returns p percent of a rounded half up to the paisa. p is a
+// decimal string such as "12" or "8.33" so that the rate stays exact.
+func (a Amount) Percent(p string) Amount {
+	r, ok := new(big.Rat).SetString(p)
+	if !ok {
+		panic(fmt.Sprintf("money: invalid percentage %q", p))
+	}
+	return a.MulRat(r.Quo(r, big.NewRat(100, 1)))
+}
+
+// CeilRupee rounds a up to the next whole rupee, as ESI contributions are.
+func (a Amount) CeilRupee() Amount {
+	if a%100 == 0 || a < 0 {
+		return a / 100 * 100
+	}
+	return (a/100 + 1) * 100
+}
+
+// Min returns the smaller of a and b.
+func Min(a, b Amount) Amount {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger of a and b.
+func Max(a, b Amount) Amount {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Sum adds up amounts.
+func Sum(amounts ...Amount) Amount {
+	var total Amount
+	for _, a := range amounts {
+		total += a
+	}
+	return total
+}
+
+// fromRat rounds r (a number of paise) half up to a multiple of unit paise.
+func fromRat(r *big.Rat, unit int64) (Amount, error) {
+	q := new(big.Rat).Quo(r, big.NewRat(unit, 1))
+	num, den := q.Num(), q.Denom()
+	whole, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// Round half away from zero: compare 2*|rem| against den.
+	rem.Abs(rem).Lsh(rem, 1)
+	if rem.Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			whole.Sub(whole, big.NewInt(1))
+		} else {
+			whole.Add(whole, big.NewInt(1))
+		}
+	}
+	if !whole.IsInt64() {
+		return 0, fmt.Errorf("amount out of range")
+	}
+	return Amount(whole.Int64() * unit), nil
+}
+
+// MarshalText encodes a as a decimal rupee string, e.g. "1234.50".
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText parses a decimal rupee string as accepted by Parse, so that
+// config files can write amounts in rupees ("15000", "1,800.50").
+func (a *Amount) UnmarshalText(text []byte) error {
+	v, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
@@ -0,0 +1,117 @@
+package money
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+	}{
+		{"", 0},
+		{"0", 0},
+		{"12,500", 1250000},
+		{" 1234.5 ", 123450},
+		{"0.01", 1},
+		{"-10.05", -1005},
+		{"-0.5", -50},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"abc", "12.5.0", "₹100", "12/5", "0x10", "1e3", "10.005", "+5", ".5", "5.", "Inf"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q): want error", in)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Amount
+		want string
+	}{
+		{0, "0.00"},
+		{123450, "1234.50"},
+		{-5, "-0.05"},
+		{-50, "-0.50"},
+		{-123456, "-1234.56"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	tests := []struct {
+		a        Amount
+		num, den int64
+		want     Amount
+	}{
+		{Rupees(15000), 12, 100, Rupees(1800)},
+		{1, 1, 2, 1},     // 0.5 paisa rounds up
+		{-1, 1, 2, -1},   // and away from zero
+		{3, 1, 2, 2},     // 1.5
+		{-3, 1, 2, -2},   // -1.5
+		{1, 49, 100, 0},  // 0.49
+		{-1, 49, 100, 0}, // -0.49
+		{Rupees(30000), 17, 30, Rupees(17000)},
+		{Rupees(10000), 1, 3, 333333},
+		{Rupees(-10000), 2, 3, -666667},
+		{Rupees(100), 1, 0, 0}, // no basis
+	}
+	for _, tt := range tests {
+		if got := tt.a.MulDiv(tt.num, tt.den); got != tt.want {
+			t.Errorf("Amount(%d).MulDiv(%d, %d) = %d, want %d", tt.a, tt.num, tt.den, got, tt.want)
+		}
+	}
+}
+
+func TestRounding(t *testing.T) {
+	tests := []struct {
+		r    Rounding
+		in   Amount
+		want Amount
+	}{
+		{ToPaisa, 12345, 12345},
+		{ToRupee, 12349, 12300},
+		{ToRupee, 12350, 12400},
+		{ToRupee, -12350, -12400},
+		{ToRupee, -12349, -12300},
+		{ToRupee, 50, 100},
+		{ToRupee, -50, -100},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Apply(tt.in); got != tt.want {
+			t.Errorf("%s.Apply(%d) = %d, want %d", tt.r, tt.in, got, tt.want)
+		}
+	}
+	for in, want := range map[string]Rounding{"": ToPaisa, "paisa": ToPaisa, "Rupee": ToRupee} {
+		if got, err := ParseRounding(in); err != nil || got != want {
+			t.Errorf("ParseRounding(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseRounding("dollar"); err == nil {
+		t.Error("ParseRounding(\"dollar\"): want error")
+	}
+}
+
+func TestPercentAndCeilRupee(t *testing.T) {
+	if got := Rupees(21000).Percent("0.75"); got != 15750 {
+		t.Errorf("Percent(0.75) = %d, want 15750", got)
+	}
+	if got := Amount(15751).CeilRupee(); got != 15800 {
+		t.Errorf("CeilRupee(157.51) = %d, want 15800", got)
+	}
+	if got := Amount(15800).CeilRupee(); got != 15800 {
+		t.Errorf("CeilRupee(158.00) = %d, want 15800", got)
+	}
+}
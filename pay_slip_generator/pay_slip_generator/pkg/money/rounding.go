@@ -0,0 +1,44 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Rounding selects the precision computed amounts are rounded to.
+type Rounding int
+
+const (
+	// ToPaisa keeps amounts exact to the paisa.
+	ToPaisa Rounding = iota
+	// ToRupee rounds amounts half up to the nearest rupee.
+	ToRupee
+)
+
+// ParseRounding accepts "paisa" or "rupee".
+func ParseRounding(s string) (Rounding, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "paisa", "paise":
+		return ToPaisa, nil
+	case "rupee", "rupees":
+		return ToRupee, nil
+	}
+	return ToPaisa, fmt.Errorf("unknown rounding %q (want paisa or rupee)", s)
+}
+
+func (r Rounding) String() string {
+	if r == ToRupee {
+		return "rupee"
+	}
+	return "paisa"
+}
+
+// Apply rounds a half up according to r.
+func (r Rounding) Apply(a Amount) Amount {
+	if r != ToRupee {
+		return a
+	}
+	out, _ := fromRat(new(big.Rat).SetInt64(int64(a)), 100)
+	return out
+}
@@ -0,0 +1,202 @@
+package payroll
+
+import (
+	"fmt"
+	"os"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// metroCities get 50% of basic as the HRA exemption limit; other cities 40%.
+var metroCities = map[string]bool{
+	"delhi": true, "new delhi": true, "mumbai": true, "kolkata": true, "chennai": true,
+}
+
+// Rent is a period for which the employee declared rent paid. To is
+// inclusive; zero means until the end of the financial year.
+type Rent struct {
+	From    time.Time    `yaml:"from"`
+	To      time.Time    `yaml:"to"`
+	Monthly money.Amount `yaml:"monthly"`
+	City    string       `yaml:"city"`
+	Metro   bool         `yaml:"metro"` // for metros not in the built-in list
+}
+
+// Declaration is an employee's tax declaration for one financial year.
+// Non-zero values replace the corresponding sheet values.
+type Declaration struct {
+	Employee   string       `yaml:"employee"` // email, PAN or name
+	FY         string       `yaml:"fy"`       // e.g. "2025-26"
+	Regime     string       `yaml:"regime"`
+	Section80C money.Amount `yaml:"80C"`
+	Section80D money.Amount `yaml:"80D"`
+	Rent       []Rent       `yaml:"rent"`
+}
+
+// Declarations holds the declarations of every employee.
+type Declarations struct {
+	byKey map[string]*Declaration // by lower-case employee and FY
+}
+
+// LoadDeclarations reads tax declarations from a YAML or JSON file:
+//
+//	declarations:
+//	  - employee: asha@example.com
+//	    fy: 2025-26
+//	    regime: old
+//	    80C: 150000
+//	    rent:
+//	      - {from: 2025-04-01, monthly: 25000, city: Hyderabad}
+func LoadDeclarations(path string) (*Declarations, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Declarations []Declaration `yaml:"declarations"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse declarations %s: %w", path, err)
+	}
+
+	d := &Declarations{byKey: make(map[string]*Declaration)}
+	for i := range file.Declarations {
+		decl := &file.Declarations[i]
+		switch {
+		case decl.Employee == "":
+			return nil, fmt.Errorf("declarations %s: entry %d has no employee", path, i+1)
+		case decl.FY == "":
+			return nil, fmt.Errorf("declarations %s: %s: missing fy", path, decl.Employee)
+		}
+		switch strings.ToLower(decl.Regime) {
+		case "", OldRegime, NewRegime:
+		default:
+			return nil, fmt.Errorf("declarations %s: %s: unknown regime %q", path, decl.Employee, decl.Regime)
+		}
+		key := declarationKey(decl.Employee, decl.FY)
+		if _, dup := d.byKey[key]; dup {
+			return nil, fmt.Errorf("declarations %s: %s has two declarations for FY %s", path, decl.Employee, decl.FY)
+		}
+		d.byKey[key] = decl
+	}
+	return d, nil
+}
+
+func declarationKey(employee, fy string) string {
+	return strings.ToLower(strings.TrimSpace(employee)) + "|" + fy
+}
+
+// Lookup returns emp's declaration for a financial year, matching on email,
+// then PAN, then name.
+func (d *Declarations) Lookup(emp *model.Employee, fy string) *Declaration {
+	for _, id := range []string{emp.Email, emp.PAN, emp.Name} {
+		if strings.TrimSpace(id) == "" {
+			continue
+		}
+		if decl, ok := d.byKey[declarationKey(id, fy)]; ok {
+			return decl
+		}
+	}
+	return nil
+}
+
+// rentFor returns the rent declared for the pay month starting at month.
+func (decl *Declaration) rentFor(month time.Time) (Rent, bool) {
+	for _, r := range decl.Rent {
+		if month.Before(r.From.AddDate(0, 0, 1-r.From.Day())) {
+			continue
+		}
+		if !r.To.IsZero() && month.After(r.To) {
+			continue
+		}
+		return r, true
+	}
+	return Rent{}, false
+}
+
+// applyDeclaration copies emp's declared regime and deductions onto it and,
+// on the old regime, computes the HRA exemption for the financial year
+// starting at fyStart.
+func (e *Engine) applyDeclaration(emp *model.Employee, fy string, fyStart, month time.Time) []Mismatch {
+	decl := e.Declarations.Lookup(emp, fy)
+	if decl == nil {
+		return nil
+	}
+	if decl.Regime != "" {
+		emp.TaxRegime = strings.ToLower(decl.Regime)
+	}
+	if decl.Section80C != 0 {
+		emp.Declared80C = decl.Section80C
+	}
+	if decl.Section80D != 0 {
+		emp.Declared80D = decl.Section80D
+	}
+	if !strings.EqualFold(emp.TaxRegime, OldRegime) || len(decl.Rent) == 0 {
+		return nil
+	}
+
+	var mismatches []Mismatch
+	months, exemption := hraExemption(emp, decl, fyStart, month)
+	if emp.HRAExemption != 0 && emp.HRAExemption != exemption {
+		mismatches = append(mismatches, amountMismatch("HRA Exemption", emp.HRAExemption, exemption))
+	}
+	emp.HRAExemption = exemption
+	emp.HRAExemptionMonths = months
+	return mismatches
+}
+
+// hraExemption computes the HRA exemption u/s 10(13A) month by month over
+// the financial year: the least of the HRA received, rent paid less 10% of
+// basic, and 50% (metro) or 40% of basic. The pay month uses its actual
+// amounts; every other month the full-month rates.
+func hraExemption(emp *model.Employee, decl *Declaration, fyStart, month time.Time) ([]model.HRAExemptionMonth, money.Amount) {
+	fullBasic, fullHRA := emp.BasicPayRate, emp.HRARate
+	if fullBasic == 0 {
+		fullBasic = emp.BasicPayAmount
+	}
+	if fullHRA == 0 {
+		fullHRA = emp.HRAAmount
+	}
+
+	var months []model.HRAExemptionMonth
+	var total money.Amount
+	for m := fyStart; m.Before(fyStart.AddDate(1, 0, 0)); m = m.AddDate(0, 1, 0) {
+		if !emp.DOJ.IsZero() && emp.DOJ.After(m.AddDate(0, 1, -1)) {
+			continue
+		}
+		if !emp.ExitDate.IsZero() && emp.ExitDate.Before(m) {
+			continue
+		}
+		rent, ok := decl.rentFor(m)
+		if !ok {
+			continue
+		}
+
+		basic, hra := fullBasic, fullHRA
+		if m.Equal(month) {
+			basic, hra = emp.BasicPayAmount, emp.HRAAmount
+		}
+		metro := rent.Metro || metroCities[strings.ToLower(strings.TrimSpace(rent.City))]
+		share := "40"
+		if metro {
+			share = "50"
+		}
+
+		row := model.HRAExemptionMonth{
+			Month:         m,
+			HRA:           hra,
+			Rent:          rent.Monthly,
+			RentLessBasic: max(rent.Monthly-basic.Percent("10"), 0),
+			BasicShare:    basic.Percent(share),
+			Metro:         metro,
+		}
+		row.Exempt = money.Min(row.HRA, money.Min(row.RentLessBasic, row.BasicShare))
+		months = append(months, row)
+		total += row.Exempt
+	}
+	return months, total
+}
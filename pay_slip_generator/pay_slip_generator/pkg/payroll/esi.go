@@ -0,0 +1,110 @@
+package payroll
+
+import (
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"strings"
+	"time"
+)
+
+// Names of the components written by the ESI computation.
+const (
+	ComponentESI         = "ESI"
+	ComponentEmployerESI = "Employer ESI"
+)
+
+// ESIRules are the Employees' State Insurance parameters. Contributions are
+// rounded up to the next rupee, as ESIC does.
+type ESIRules struct {
+	// WageThreshold is the highest monthly gross wage that is covered.
+	WageThreshold money.Amount
+	// ExcludedComponents are earnings that are not ESI wages.
+	ExcludedComponents []string
+
+	EmployeeRate string // % of ESI wages deducted from the employee
+	EmployerRate string // % of ESI wages paid by the employer
+}
+
+// DefaultESI returns the statutory rates: 0.75% employee and 3.25% employer
+// share for employees earning up to 21,000 a month.
+func DefaultESI() *ESIRules {
+	return &ESIRules{
+		WageThreshold: money.Rupees(21000),
+		EmployeeRate:  "0.75",
+		EmployerRate:  "3.25",
+	}
+}
+
+// ContributionPeriodStart returns the first month of the ESI contribution
+// period (April-September or October-March) the pay month starting at month
+// falls in.
+func ContributionPeriodStart(month time.Time) time.Time {
+	start := time.April
+	year := month.Year()
+	switch {
+	case month.Month() >= time.October:
+		start = time.October
+	case month.Month() < time.April:
+		start = time.October
+		year--
+	}
+	return time.Date(year, start, 1, 0, 0, 0, 0, month.Location())
+}
+
+// covered reports whether emp is insured for the pay month starting at month.
+// Eligibility is decided on the full-month wages; an employee already covered
+// earlier in the contribution period stays covered until it ends even when
+// the wages rise above the threshold.
+func (r *ESIRules) covered(emp *model.Employee, month time.Time, wages money.Amount) bool {
+	if wages <= r.WageThreshold {
+		return true
+	}
+	return emp.ESICovered && !month.Equal(ContributionPeriodStart(month))
+}
+
+// computeESI sets the employee ESI deduction and the employer ESI
+// contribution for covered employees, and clears them for everyone else.
+func (e *Engine) computeESI(emp *model.Employee) []Mismatch {
+	r := e.ESI
+	var rateWages, wages money.Amount
+	for _, c := range emp.Earnings() {
+		if r.excluded(c.Name) {
+			continue
+		}
+		if c.Rate > 0 {
+			rateWages += c.Rate
+		} else {
+			rateWages += c.Amount
+		}
+		wages += c.Amount
+	}
+
+	var employee, employer money.Amount
+	month, ok := emp.PeriodStart()
+	if ok && r.covered(emp, month, rateWages) {
+		emp.ESICovered = true
+		employee = wages.Percent(r.EmployeeRate).CeilRupee()
+		employer = wages.Percent(r.EmployerRate).CeilRupee()
+	} else {
+		emp.ESICovered = false
+	}
+
+	var mismatches []Mismatch
+	for _, c := range emp.ExtraDeductions {
+		if strings.EqualFold(c.Name, ComponentESI) && c.Amount != employee {
+			mismatches = append(mismatches, amountMismatch(ComponentESI, c.Amount, employee))
+		}
+	}
+	emp.ExtraDeductions = setComponent(emp.ExtraDeductions, ComponentESI, employee)
+	emp.EmployerContributions = setComponent(emp.EmployerContributions, ComponentEmployerESI, employer)
+	return mismatches
+}
+
+func (r *ESIRules) excluded(name string) bool {
+	for _, x := range r.ExcludedComponents {
+		if strings.EqualFold(x, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+// Package payroll owns the pay computation. Gross earnings, total deductions
+// and net pay are derived here and nowhere else, so every binary produces the
+// same figures for the same input.
+package payroll
+
+import (
+	"fmt"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"strings"
+)
+
+// Engine computes payslips. The zero value keeps amounts exact to the paisa,
+// prorates on calendar days and takes every earning from the sheet.
+type Engine struct {
+	Rounding  money.Rounding
+	Proration Proration
+
+	// Structures, when set, derive the earnings of employees that have an
+	// annual CTC and a salary structure name.
+	Structures *Structures
+
+	// Statutory computations; nil means the sheet's value is used as is.
+	PF  *PFRules
+	ESI *ESIRules
+	PT  *PTTable
+	Tax *TaxTables // income tax (TDS)
+
+	// Declarations, when set, supply tax regimes, deductions and rent for
+	// the HRA exemption to the TDS computation.
+	Declarations *Declarations
+}
+
+// EnableStatutory turns on the statutory computations named in list
+// (comma separated: pf, esi, pt, tds) with their default rules.
+func (e *Engine) EnableStatutory(list string) error {
+	for _, name := range strings.Split(list, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "pf":
+			e.PF = DefaultPF()
+		case "esi":
+			e.ESI = DefaultESI()
+		case "pt":
+			e.PT = DefaultPT()
+		case "tds":
+			e.Tax = DefaultTax()
+		default:
+			return fmt.Errorf("unknown statutory computation %q (want pf, esi, pt or tds)", name)
+		}
+	}
+	return nil
+}
+
+// Mismatch is a value supplied in the input that differs from the computed
+// one. Values are formatted for display (amounts in rupees, days as numbers).
+type Mismatch struct {
+	Field    string
+	Supplied string
+	Computed string
+}
+
+func amountMismatch(field string, supplied, computed money.Amount) Mismatch {
+	return Mismatch{Field: field, Supplied: supplied.String(), Computed: computed.String()}
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s in sheet is %s, computed %s", m.Field, m.Supplied, m.Computed)
+}
+
+// Compute derives emp's pay: the earnings from its salary structure when it
+// has one, prorated for loss of pay, the enabled statutory deductions, then
+// the totals from the earning and deduction components. The
+// computed values always replace the ones supplied in the sheet; every
+// disagreement is returned so it can be reported. Values left empty (zero) in
+// the sheet are not reconciled.
+func (e *Engine) Compute(emp *model.Employee) ([]Mismatch, error) {
+	var mismatches []Mismatch
+	if emp.AnnualCTC != 0 && emp.SalaryStructure != "" {
+		if err := e.applyStructure(emp); err != nil {
+			return nil, err
+		}
+	}
+
+	mismatches = append(mismatches, e.prorate(emp)...)
+	if e.PF != nil {
+		mismatches = append(mismatches, e.computePF(emp)...)
+	}
+	if e.ESI != nil {
+		mismatches = append(mismatches, e.computeESI(emp)...)
+	}
+	if e.PT != nil {
+		m, err := e.computePT(emp)
+		if err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, m...)
+	}
+	if e.Tax != nil {
+		m, err := e.computeTDS(emp)
+		if err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, m...)
+	}
+	e.roundComponents(emp)
+
+	var gross, deductions money.Amount
+	for _, c := range emp.Earnings() {
+		gross += c.Amount
+	}
+	for _, c := range emp.Deductions() {
+		deductions += c.Amount
+	}
+	net := gross - deductions
+
+	reconcile := func(field string, supplied *money.Amount, computed money.Amount) {
+		if *supplied != 0 && *supplied != computed {
+			mismatches = append(mismatches, amountMismatch(field, *supplied, computed))
+		}
+		*supplied = computed
+	}
+	reconcile("Gross Earnings", &emp.GrossEarnings, gross)
+	reconcile("Total Deductions", &emp.TotalDeductions, deductions)
+	reconcile("Net Pay", &emp.NetPay, net)
+	return mismatches, nil
+}
+
+// roundComponents applies the engine's rounding to every earning and deduction.
+func (e *Engine) roundComponents(emp *model.Employee) {
+	for _, p := range []*money.Amount{
+		&emp.BasicPayAmount, &emp.HRAAmount, &emp.OtherAllowanceAmount,
+		&emp.ProfessionalTax, &emp.PF, &emp.IncomeTax,
+	} {
+		*p = e.Rounding.Apply(*p)
+	}
+	for i := range emp.ExtraEarnings {
+		emp.ExtraEarnings[i].Amount = e.Rounding.Apply(emp.ExtraEarnings[i].Amount)
+	}
+	for i := range emp.ExtraDeductions {
+		emp.ExtraDeductions[i].Amount = e.Rounding.Apply(emp.ExtraDeductions[i].Amount)
+	}
+}
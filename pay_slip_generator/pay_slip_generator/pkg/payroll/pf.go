@@ -0,0 +1,117 @@
+package payroll
+
+import (
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"strconv"
+	"strings"
+)
+
+// Names of the components written by the PF computation.
+const (
+	ComponentVPF         = "VPF"
+	ComponentEmployerEPF = "Employer EPF"
+	ComponentEmployerEPS = "Employer EPS"
+)
+
+// PFRules are the Employees' Provident Fund parameters. Contributions are
+// rounded to the nearest rupee, as EPFO does.
+type PFRules struct {
+	// WageCeiling caps the PF wages contributions are computed on, unless the
+	// employee contributes on full basic (model.Employee.PFOnFullBasic).
+	WageCeiling money.Amount
+	// WageComponents are the earnings that make up PF wages.
+	WageComponents []string
+
+	EmployeeRate string // % of PF wages deducted from the employee
+	EmployerRate string // % of PF wages paid by the employer, EPS included
+	EPSRate      string // % of PF wages (always capped) diverted to pension
+
+	// ReplacesEmployer names an employer contribution (e.g. from a salary
+	// structure) that the EPF/EPS split replaces.
+	ReplacesEmployer string
+}
+
+// DefaultPF returns the statutory rates: 12% employee and employer share on
+// PF wages capped at 15,000, of which 8.33% goes to EPS.
+func DefaultPF() *PFRules {
+	return &PFRules{
+		WageCeiling:      money.Rupees(15000),
+		WageComponents:   []string{"Basic Pay", "Dearness Allowance"},
+		EmployeeRate:     "12",
+		EmployerRate:     "12",
+		EPSRate:          "8.33",
+		ReplacesEmployer: "Employer PF",
+	}
+}
+
+// computePF sets the employee PF deduction, VPF and the employer EPF/EPS
+// contributions from the employee's (prorated) PF wages.
+func (e *Engine) computePF(emp *model.Employee) []Mismatch {
+	r := e.PF
+	var wages money.Amount
+	for _, c := range emp.Earnings() {
+		for _, name := range r.WageComponents {
+			if strings.EqualFold(c.Name, name) {
+				wages += c.Amount
+			}
+		}
+	}
+
+	capped := money.Min(wages, r.WageCeiling)
+	contributory := capped
+	if emp.PFOnFullBasic {
+		contributory = wages
+	}
+
+	employee := money.ToRupee.Apply(contributory.Percent(r.EmployeeRate))
+	eps := money.ToRupee.Apply(capped.Percent(r.EPSRate))
+	epf := money.ToRupee.Apply(contributory.Percent(r.EmployerRate)) - eps
+
+	var mismatches []Mismatch
+	if emp.PF != 0 && emp.PF != employee {
+		mismatches = append(mismatches, amountMismatch("PF", emp.PF, employee))
+	}
+	emp.PF = employee
+
+	var vpf money.Amount
+	if emp.VPFPercent > 0 {
+		vpf = money.ToRupee.Apply(contributory.Percent(strconv.FormatFloat(emp.VPFPercent, 'f', -1, 64)))
+	}
+	emp.ExtraDeductions = setComponent(emp.ExtraDeductions, ComponentVPF, vpf)
+
+	// The split replaces any lump-sum employer PF, e.g. from a salary structure.
+	var replaced money.Amount
+	contributions := emp.EmployerContributions[:0:0]
+	for _, c := range emp.EmployerContributions {
+		if strings.EqualFold(c.Name, r.ReplacesEmployer) {
+			replaced += c.Amount
+			continue
+		}
+		contributions = append(contributions, c)
+	}
+	if replaced != 0 && replaced != epf+eps {
+		mismatches = append(mismatches, amountMismatch(r.ReplacesEmployer, replaced, epf+eps))
+	}
+	contributions = setComponent(contributions, ComponentEmployerEPF, epf)
+	emp.EmployerContributions = setComponent(contributions, ComponentEmployerEPS, eps)
+	return mismatches
+}
+
+// setComponent sets the amount of the named component in comps, appending it
+// when missing and dropping it when amount is zero.
+func setComponent(comps []model.Component, name string, amount money.Amount) []model.Component {
+	for i := range comps {
+		if strings.EqualFold(comps[i].Name, name) {
+			if amount == 0 {
+				return append(comps[:i], comps[i+1:]...)
+			}
+			comps[i].Amount = amount
+			return comps
+		}
+	}
+	if amount == 0 {
+		return comps
+	}
+	return append(comps, model.Component{Name: name, Amount: amount})
+}
@@ -0,0 +1,237 @@
+package payroll
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Basis selects how many standard days a pay month has for proration.
+type Basis int
+
+const (
+	// CalendarDays uses the number of days in the month (28-31).
+	CalendarDays Basis = iota
+	// ThirtyDays treats every month as 30 days.
+	ThirtyDays
+	// WorkingDays counts the days that are not weekly offs or holidays in
+	// the Engine's calendar.
+	WorkingDays
+)
+
+// ParseBasis accepts "calendar", "30day" or "working".
+func ParseBasis(s string) (Basis, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "calendar":
+		return CalendarDays, nil
+	case "30day", "30", "thirty":
+		return ThirtyDays, nil
+	case "working", "working-days":
+		return WorkingDays, nil
+	}
+	return CalendarDays, fmt.Errorf("unknown proration basis %q (want calendar, 30day or working)", s)
+}
+
+func (b Basis) String() string {
+	switch b {
+	case ThirtyDays:
+		return "30day"
+	case WorkingDays:
+		return "working"
+	}
+	return "calendar"
+}
+
+// Calendar lists the non-working days used by the WorkingDays basis.
+type Calendar struct {
+	WeeklyOff []time.Weekday
+	Holidays  map[string]bool // keyed by "2006-01-02"
+}
+
+// LoadCalendar reads a holiday calendar from a YAML or JSON file:
+//
+//	weekly_off: [Sunday]
+//	holidays: [2024-01-26, 2024-08-15, 2024-10-02]
+//
+// Without weekly_off, Sunday is the only weekly off.
+func LoadCalendar(path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		WeeklyOff []string    `yaml:"weekly_off"`
+		Holidays  []time.Time `yaml:"holidays"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse holidays %s: %w", path, err)
+	}
+
+	c := &Calendar{Holidays: make(map[string]bool, len(file.Holidays))}
+	for _, name := range file.WeeklyOff {
+		day, ok := weekdays[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("holidays %s: unknown weekday %q", path, name)
+		}
+		c.WeeklyOff = append(c.WeeklyOff, day)
+	}
+	if file.WeeklyOff == nil {
+		c.WeeklyOff = []time.Weekday{time.Sunday}
+	}
+	for _, d := range file.Holidays {
+		c.Holidays[d.Format("2006-01-02")] = true
+	}
+	return c, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday,
+	"wednesday": time.Wednesday, "thursday": time.Thursday, "friday": time.Friday,
+	"saturday": time.Saturday,
+}
+
+// IsWorkingDay reports whether d is neither a weekly off nor a holiday. A nil
+// Calendar only has Sundays off.
+func (c *Calendar) IsWorkingDay(d time.Time) bool {
+	if c == nil {
+		return d.Weekday() != time.Sunday
+	}
+	for _, off := range c.WeeklyOff {
+		if d.Weekday() == off {
+			return false
+		}
+	}
+	return !c.Holidays[d.Format("2006-01-02")]
+}
+
+// Proration scales monthly earnings by the share of the month that is paid.
+type Proration struct {
+	Basis    Basis
+	Calendar *Calendar // used by WorkingDays
+}
+
+// days counts the standard days of the pay month starting at month that
+// fall within [from, to] (inclusive). On the ThirtyDays basis a full month is
+// 30 days and every calendar day outside [from, to] is taken off that.
+func (p Proration) days(month, from, to time.Time) float64 {
+	last := month.AddDate(0, 1, -1)
+	if from.Before(month) {
+		from = month
+	}
+	if to.After(last) {
+		to = last
+	}
+	if to.Before(from) {
+		return 0
+	}
+
+	switch p.Basis {
+	case ThirtyDays:
+		outside := calendarDays(month, last) - calendarDays(from, to)
+		return max(30-outside, 0)
+	case WorkingDays:
+		n := 0.0
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			if p.Calendar.IsWorkingDay(d) {
+				n++
+			}
+		}
+		return n
+	}
+	return calendarDays(from, to)
+}
+
+func calendarDays(from, to time.Time) float64 {
+	return float64(to.Sub(from)/(24*time.Hour) + 1)
+}
+
+// StandardDays returns the number of standard days in the pay month
+// starting at month.
+func (p Proration) StandardDays(month time.Time) float64 {
+	return p.days(month, month, month.AddDate(0, 1, -1))
+}
+
+// EmployedDays returns the standard days of the pay month starting at month
+// during which the employee was on the rolls, given the date of joining and
+// exit date (zero when not applicable).
+func (p Proration) EmployedDays(month, doj, exit time.Time) float64 {
+	from, to := month, month.AddDate(0, 1, -1)
+	if !doj.IsZero() && doj.After(from) {
+		from = doj
+	}
+	if !exit.IsZero() && exit.Before(to) {
+		to = exit
+	}
+	return p.days(month, from, to)
+}
+
+// prorate scales every earning (and employer contribution) that has a
+// standard rate to the payable share of the month, accounting for loss of pay
+// and for joining or leaving within the month, and records the attendance on
+// emp. Earnings without a rate are
+// taken as final amounts. Sheet amounts and attendance that disagree with the
+// computed ones are returned as mismatches.
+func (e *Engine) prorate(emp *model.Employee) []Mismatch {
+	var mismatches []Mismatch
+
+	std, employed := 30.0, 30.0
+	if month, ok := emp.PeriodStart(); ok {
+		std = e.Proration.StandardDays(month)
+		employed = e.Proration.EmployedDays(month, emp.DOJ, emp.ExitDate)
+	}
+
+	// Attendance: LOP is the input; payable days are the days on the rolls
+	// (after joining, up to exit) less LOP. A sheet that only has payable
+	// days still works by deriving LOP the other way.
+	supplied := emp.PayableDays
+	if emp.LOPDays == 0 && supplied > 0 && supplied < employed {
+		emp.LOPDays = employed - supplied
+	}
+	payable := max(employed-emp.LOPDays, 0)
+
+	if emp.StandardDays != 0 && emp.StandardDays != std {
+		mismatches = append(mismatches, Mismatch{Field: "Standard Days", Supplied: formatDays(emp.StandardDays), Computed: formatDays(std)})
+	}
+	if supplied != 0 && supplied != payable {
+		mismatches = append(mismatches, Mismatch{Field: "Payable Days", Supplied: formatDays(supplied), Computed: formatDays(payable)})
+	}
+	emp.StandardDays, emp.PayableDays = std, payable
+
+	share := new(big.Rat).SetFloat64(payable)
+	if std > 0 {
+		share.Quo(share, new(big.Rat).SetFloat64(std))
+	}
+	scale := func(name string, rate money.Amount, amount *money.Amount) {
+		if rate == 0 {
+			return
+		}
+		computed := e.Rounding.Apply(rate.MulRat(share))
+		if *amount != 0 && *amount != computed {
+			mismatches = append(mismatches, amountMismatch(name, *amount, computed))
+		}
+		*amount = computed
+	}
+	scale("Basic Pay", emp.BasicPayRate, &emp.BasicPayAmount)
+	scale("House Rent Allowance", emp.HRARate, &emp.HRAAmount)
+	scale("Other Allowance", emp.OtherAllowanceRate, &emp.OtherAllowanceAmount)
+	for i := range emp.ExtraEarnings {
+		c := &emp.ExtraEarnings[i]
+		scale(c.Name, c.Rate, &c.Amount)
+	}
+	for i := range emp.EmployerContributions {
+		c := &emp.EmployerContributions[i]
+		scale(c.Name, c.Rate, &c.Amount)
+	}
+	return mismatches
+}
+
+func formatDays(d float64) string {
+	return strconv.FormatFloat(d, 'f', -1, 64)
+}
@@ -0,0 +1,220 @@
+package payroll
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed pt_slabs.yaml
+var builtinPTSlabs []byte
+
+// PTSlab is one band of a professional tax table. Upto is the highest
+// monthly gross the band covers; zero means no upper limit.
+type PTSlab struct {
+	Upto     money.Amount `yaml:"upto"`
+	Tax      money.Amount `yaml:"tax"`
+	February money.Amount `yaml:"february"` // replaces Tax in February when set
+}
+
+// PTState is one version of a state's professional tax slabs.
+type PTState struct {
+	State         string    `yaml:"state"`
+	Aliases       []string  `yaml:"aliases"`
+	Gender        string    `yaml:"gender"` // "" for everyone, or male/female
+	EffectiveFrom time.Time `yaml:"effective_from"`
+	Slabs         []PTSlab  `yaml:"slabs"`
+}
+
+// PTTable holds the professional tax slabs of every state.
+type PTTable struct {
+	byState map[string][]PTState // by lower-case state name, newest first
+	names   map[string]string    // lower-case names and aliases to byState keys
+}
+
+// DefaultPT returns the built-in slabs (Telangana, Karnataka, Maharashtra).
+func DefaultPT() *PTTable {
+	t, err := parsePT(builtinPTSlabs)
+	if err != nil {
+		panic(fmt.Sprintf("payroll: built-in PT slabs: %v", err))
+	}
+	return t
+}
+
+// LoadPT reads professional tax slabs from a YAML or JSON file in the format
+// of pt_slabs.yaml and lays them over the built-in ones: a state in the file,
+// named by its name or any of its built-in aliases, replaces every built-in
+// version of that state, other states are kept.
+func LoadPT(path string) (*PTTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	override, err := parsePT(data)
+	if err != nil {
+		return nil, fmt.Errorf("PT slabs %s: %w", path, err)
+	}
+	t := DefaultPT()
+	target := make(map[string]string) // override state to t's state
+	for name, state := range override.names {
+		if builtin, ok := t.names[name]; ok {
+			target[state] = builtin
+		}
+	}
+	replaced := make(map[string]bool)
+	for state, versions := range override.byState {
+		to, ok := target[state]
+		if !ok {
+			to = state
+		}
+		if !replaced[to] {
+			t.byState[to], replaced[to] = nil, true
+		}
+		t.byState[to] = append(t.byState[to], versions...)
+	}
+	for name, state := range override.names {
+		if to, ok := target[state]; ok {
+			state = to
+		}
+		t.names[name] = state
+	}
+	t.sort()
+	return t, nil
+}
+
+func parsePT(data []byte) (*PTTable, error) {
+	var file struct {
+		States []PTState `yaml:"states"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse PT slabs: %w", err)
+	}
+
+	t := &PTTable{byState: make(map[string][]PTState), names: make(map[string]string)}
+	for _, st := range file.States {
+		if err := st.validate(); err != nil {
+			return nil, err
+		}
+		state := ptKey(st.State)
+		t.byState[state] = append(t.byState[state], st)
+		for _, name := range append([]string{st.State}, st.Aliases...) {
+			t.names[ptKey(name)] = state
+		}
+	}
+	t.sort()
+	return t, nil
+}
+
+// sort puts every state's versions newest first.
+func (t *PTTable) sort() {
+	for _, versions := range t.byState {
+		sort.SliceStable(versions, func(i, j int) bool {
+			return versions[i].EffectiveFrom.After(versions[j].EffectiveFrom)
+		})
+	}
+}
+
+func ptKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (st *PTState) validate() error {
+	if st.State == "" {
+		return fmt.Errorf("PT slabs without a state")
+	}
+	switch st.Gender {
+	case "", "male", "female":
+	default:
+		return fmt.Errorf("PT slabs %s: gender must be male or female, not %q", st.State, st.Gender)
+	}
+	for i, s := range st.Slabs {
+		if s.Upto == 0 && i != len(st.Slabs)-1 {
+			return fmt.Errorf("PT slabs %s: only the last slab may omit upto", st.State)
+		}
+		if i > 0 && s.Upto != 0 && s.Upto <= st.Slabs[i-1].Upto {
+			return fmt.Errorf("PT slabs %s: upto must increase (%s after %s)", st.State, s.Upto, st.Slabs[i-1].Upto)
+		}
+	}
+	return nil
+}
+
+// Tax returns the professional tax on a month's gross for an employee of
+// the given gender working in state, for the pay month starting at month.
+func (t *PTTable) Tax(state, gender string, month time.Time, gross money.Amount) (money.Amount, error) {
+	versions, ok := t.byState[t.names[ptKey(state)]]
+	if !ok {
+		return 0, fmt.Errorf("no professional tax slabs for state %q", state)
+	}
+	gender = strings.ToLower(strings.TrimSpace(gender))
+	switch gender {
+	case "m":
+		gender = "male"
+	case "f":
+		gender = "female"
+	}
+
+	// A version for the employee's gender wins over a general one.
+	pick := func(g string) *PTState {
+		for i := range versions {
+			if versions[i].Gender == g && !versions[i].EffectiveFrom.After(month) {
+				return &versions[i]
+			}
+		}
+		return nil
+	}
+	var chosen *PTState
+	if gender != "" {
+		chosen = pick(gender)
+	}
+	if chosen == nil {
+		chosen = pick("")
+	}
+	if chosen == nil {
+		return 0, fmt.Errorf("no professional tax slabs for %s in effect on %s", state, month.Format("2006-01-02"))
+	}
+
+	for _, s := range chosen.Slabs {
+		if s.Upto != 0 && gross > s.Upto {
+			continue
+		}
+		if month.Month() == time.February && s.February != 0 {
+			return s.February, nil
+		}
+		return s.Tax, nil
+	}
+	return 0, nil
+}
+
+// computePT sets the professional tax of employees with a work state from the
+// month's gross earnings. Employees without a state keep the sheet's value.
+func (e *Engine) computePT(emp *model.Employee) ([]Mismatch, error) {
+	if strings.TrimSpace(emp.State) == "" {
+		return nil, nil
+	}
+	month, ok := emp.PeriodStart()
+	if !ok {
+		return nil, fmt.Errorf("cannot compute professional tax: unknown pay month %q %q", emp.Month, emp.Year)
+	}
+	var gross money.Amount
+	for _, c := range emp.Earnings() {
+		gross += c.Amount
+	}
+	tax, err := e.PT.Tax(emp.State, emp.Gender, month, gross)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []Mismatch
+	if emp.ProfessionalTax != 0 && emp.ProfessionalTax != tax {
+		mismatches = append(mismatches, amountMismatch("Professional Tax", emp.ProfessionalTax, tax))
+	}
+	emp.ProfessionalTax = tax
+	return mismatches, nil
+}
@@ -0,0 +1,64 @@
+package payroll
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pay_slip_generator/pkg/money"
+)
+
+func TestLoadPTOverridesAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pt.yaml")
+	override := `
+states:
+  - state: TG
+    effective_from: 2024-04-01
+    slabs:
+      - {upto: 15000, tax: 0}
+      - {tax: 250}
+  - state: Gujarat
+    aliases: [GJ]
+    effective_from: 2024-04-01
+    slabs:
+      - {upto: 12000, tax: 0}
+      - {tax: 200}
+`
+	if err := os.WriteFile(path, []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	table, err := LoadPT(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	month := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		state string
+		want  money.Amount
+	}{
+		{"TG", money.Rupees(250)},
+		{"TS", money.Rupees(250)}, // the other built-in alias
+		{"Telangana", money.Rupees(250)},
+		{" telangana ", money.Rupees(250)},
+		{"KA", money.Rupees(200)}, // not overridden
+		{"GJ", money.Rupees(200)}, // added
+	}
+	for _, tt := range tests {
+		got, err := table.Tax(tt.state, "", month, money.Rupees(30000))
+		if err != nil {
+			t.Errorf("Tax(%q): %v", tt.state, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Tax(%q) = %s, want %s", tt.state, got, tt.want)
+		}
+	}
+
+	// The override replaces every built-in version, so there are no
+	// Telangana slabs before it takes effect.
+	if _, err := table.Tax("TS", "", month.AddDate(-1, 0, 0), money.Rupees(30000)); err == nil {
+		t.Error("Tax before the override: want error")
+	}
+}
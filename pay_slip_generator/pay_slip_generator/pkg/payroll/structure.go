@@ -0,0 +1,248 @@
+package payroll
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Component types in a salary structure.
+const (
+	Earning  = "earning"  // paid to the employee
+	Employer = "employer" // part of CTC paid by the employer (e.g. employer PF)
+)
+
+// Rule derives one monthly component of a salary structure. A rule is either
+// a Fixed monthly amount, Percent of another amount (Of: "ctc" for the
+// monthly CTC, or the name of an earlier component, optionally capped at
+// WageCap first), or the Balance of the monthly CTC left after every other
+// component.
+type Rule struct {
+	Name    string       `yaml:"name"`
+	Type    string       `yaml:"type"`    // earning (default) or employer
+	Percent string       `yaml:"percent"` // decimal, e.g. "40" or "8.33"
+	Of      string       `yaml:"of"`
+	WageCap money.Amount `yaml:"wage_cap"`
+	Fixed   money.Amount `yaml:"fixed"`
+	Balance bool         `yaml:"balance"`
+}
+
+// Structure is a named salary structure, valid for pay months on or after
+// EffectiveFrom until a later version of the same name takes over.
+type Structure struct {
+	Name          string    `yaml:"name"`
+	EffectiveFrom time.Time `yaml:"effective_from"`
+	Components    []Rule    `yaml:"components"`
+}
+
+// Structures holds every version of every structure in a config file.
+type Structures struct {
+	byName map[string][]Structure // newest version first
+}
+
+// LoadStructures reads salary structures from a YAML or JSON file:
+//
+//	structures:
+//	  - name: standard
+//	    effective_from: 2024-04-01
+//	    components:
+//	      - {name: Basic Pay, percent: "40", of: ctc}
+//	      - {name: House Rent Allowance, percent: "50", of: Basic Pay}
+//	      - {name: Employer PF, type: employer, percent: "12", of: Basic Pay, wage_cap: 15000}
+//	      - {name: Special Allowance, balance: true}
+func LoadStructures(path string) (*Structures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Structures []Structure `yaml:"structures"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse structures %s: %w", path, err)
+	}
+
+	s := &Structures{byName: make(map[string][]Structure)}
+	for _, st := range file.Structures {
+		if err := st.validate(); err != nil {
+			return nil, fmt.Errorf("structures %s: %w", path, err)
+		}
+		key := strings.ToLower(st.Name)
+		s.byName[key] = append(s.byName[key], st)
+	}
+	for _, versions := range s.byName {
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i].EffectiveFrom.After(versions[j].EffectiveFrom)
+		})
+	}
+	return s, nil
+}
+
+// Lookup returns the version of the named structure in effect on date.
+func (s *Structures) Lookup(name string, date time.Time) (*Structure, error) {
+	if s == nil {
+		return nil, fmt.Errorf("salary structure %q: no structures loaded", name)
+	}
+	versions, ok := s.byName[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown salary structure %q", name)
+	}
+	for i := range versions {
+		if !versions[i].EffectiveFrom.After(date) {
+			return &versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("salary structure %q is not effective on %s", name, date.Format("2006-01-02"))
+}
+
+func (st *Structure) validate() error {
+	if st.Name == "" {
+		return fmt.Errorf("structure without a name")
+	}
+	seen := map[string]bool{"ctc": true}
+	balances := 0
+	for _, r := range st.Components {
+		switch {
+		case r.Name == "":
+			return fmt.Errorf("structure %s: component without a name", st.Name)
+		case r.Type != "" && r.Type != Earning && r.Type != Employer:
+			return fmt.Errorf("structure %s: %s: type must be %q or %q", st.Name, r.Name, Earning, Employer)
+		case r.Balance:
+			balances++
+		case r.Percent != "":
+			if _, ok := new(big.Rat).SetString(r.Percent); !ok {
+				return fmt.Errorf("structure %s: %s: invalid percent %q", st.Name, r.Name, r.Percent)
+			}
+			if !seen[strings.ToLower(r.Of)] {
+				return fmt.Errorf("structure %s: %s: %q must be ctc or an earlier component", st.Name, r.Name, r.Of)
+			}
+		case r.Fixed == 0:
+			return fmt.Errorf("structure %s: %s: needs percent, fixed or balance", st.Name, r.Name)
+		}
+		seen[strings.ToLower(r.Name)] = true
+	}
+	if balances > 1 {
+		return fmt.Errorf("structure %s: only one component may be the balance", st.Name)
+	}
+	return nil
+}
+
+// Derive splits annualCTC into monthly components, in structure order. Every
+// component except the balance is rounded according to rounding; the balance
+// takes whatever is left so that the components always add up to the
+// monthly CTC exactly.
+//
+// For example, with the structure above and a CTC of 6,00,000 the monthly
+// CTC is 50,000: Basic 20,000 (40%), HRA 10,000 (50% of Basic), employer PF
+// 1,800 (12% of Basic capped at 15,000) and Special Allowance 18,200.
+func (st *Structure) Derive(annualCTC money.Amount, rounding money.Rounding) ([]model.Component, []string, error) {
+	monthly := annualCTC.MulDiv(1, 12)
+	values := map[string]money.Amount{"ctc": monthly}
+	comps := make([]model.Component, len(st.Components))
+	types := make([]string, len(st.Components))
+
+	balance := -1
+	var allocated money.Amount
+	for i, r := range st.Components {
+		types[i] = r.Type
+		if types[i] == "" {
+			types[i] = Earning
+		}
+		comps[i].Name = r.Name
+		if r.Balance {
+			balance = i
+			continue
+		}
+
+		amt := r.Fixed
+		if r.Percent != "" {
+			base := values[strings.ToLower(r.Of)]
+			if r.WageCap > 0 {
+				base = money.Min(base, r.WageCap)
+			}
+			amt = base.Percent(r.Percent)
+		}
+		amt = rounding.Apply(amt)
+		comps[i].Amount = amt
+		values[strings.ToLower(r.Name)] = amt
+		allocated += amt
+	}
+
+	if balance >= 0 {
+		rest := monthly - allocated
+		if rest < 0 {
+			return nil, nil, fmt.Errorf("structure %s: components exceed monthly CTC %s by %s", st.Name, monthly, -rest)
+		}
+		comps[balance].Amount = rest
+	}
+	for i := range comps {
+		comps[i].Rate = comps[i].Amount
+	}
+	return comps, types, nil
+}
+
+// applyStructure replaces emp's standard rates with the components derived
+// from its annual CTC, and its employer contributions with the structure's
+// employer components. Sheet components the structure does not define (e.g.
+// a Bonus) are kept after the derived ones. Amounts are left to proration,
+// which also reconciles them against the sheet.
+func (e *Engine) applyStructure(emp *model.Employee) error {
+	period, ok := emp.PeriodStart()
+	if !ok {
+		return fmt.Errorf("cannot apply salary structure: unknown pay month %q %q", emp.Month, emp.Year)
+	}
+	st, err := e.Structures.Lookup(emp.SalaryStructure, period)
+	if err != nil {
+		return err
+	}
+	comps, types, err := st.Derive(emp.AnnualCTC, e.Rounding)
+	if err != nil {
+		return err
+	}
+
+	var earnings, employer []model.Component
+	for i, c := range comps {
+		if types[i] == Employer {
+			employer = append(employer, model.Component{Name: c.Name, Rate: c.Rate})
+			continue
+		}
+		switch strings.ToLower(c.Name) {
+		case "basic pay", "basic":
+			emp.BasicPayRate = c.Rate
+		case "house rent allowance", "hra":
+			emp.HRARate = c.Rate
+		case "other allowance":
+			emp.OtherAllowanceRate = c.Rate
+		default:
+			earnings = append(earnings, model.Component{Name: c.Name, Rate: c.Rate})
+		}
+	}
+	emp.ExtraEarnings = mergeComponents(earnings, emp.ExtraEarnings)
+	emp.EmployerContributions = mergeComponents(employer, emp.EmployerContributions)
+	return nil
+}
+
+// mergeComponents returns the derived components, each with the amount of
+// the sheet component of the same name so that proration can reconcile it,
+// followed by the sheet components the structure does not define.
+func mergeComponents(derived, sheet []model.Component) []model.Component {
+	byName := make(map[string]int, len(derived))
+	for i, c := range derived {
+		byName[strings.ToLower(c.Name)] = i
+	}
+	for _, c := range sheet {
+		if i, ok := byName[strings.ToLower(c.Name)]; ok {
+			derived[i].Amount = c.Amount
+			continue
+		}
+		derived = append(derived, c)
+	}
+	return derived
+}
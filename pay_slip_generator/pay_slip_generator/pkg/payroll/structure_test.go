@@ -0,0 +1,187 @@
+package payroll
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+)
+
+const testStructures = `
+structures:
+  - name: standard
+    effective_from: 2024-04-01
+    components:
+      - {name: Basic Pay, percent: "40", of: ctc}
+      - {name: House Rent Allowance, percent: "50", of: Basic Pay}
+      - {name: Employer PF, type: employer, percent: "12", of: Basic Pay, wage_cap: 15000}
+      - {name: Special Allowance, balance: true}
+  - name: standard
+    effective_from: 2025-04-01
+    components:
+      - {name: Basic Pay, percent: "50", of: ctc}
+      - {name: House Rent Allowance, percent: "40", of: Basic Pay}
+      - {name: Conveyance, fixed: 1600}
+      - {name: Employer PF, type: employer, percent: "12", of: Basic Pay, wage_cap: 15000}
+      - {name: Special Allowance, balance: true}
+  - name: top-heavy
+    effective_from: 2024-04-01
+    components:
+      - {name: Basic Pay, fixed: 60000}
+      - {name: Special Allowance, balance: true}
+`
+
+func loadTestStructures(t *testing.T) *Structures {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "structures.yaml")
+	if err := os.WriteFile(path, []byte(testStructures), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadStructures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// checkDerive compares derived components against want, given in rupees.
+func checkDerive(t *testing.T, comps []model.Component, want map[string]string) {
+	t.Helper()
+	if len(comps) != len(want) {
+		t.Errorf("got %d components, want %d", len(comps), len(want))
+	}
+	for _, c := range comps {
+		if w, ok := want[c.Name]; !ok || c.Amount != money.MustParse(w) || c.Rate != c.Amount {
+			t.Errorf("%s = %s (rate %s), want %s", c.Name, c.Amount, c.Rate, w)
+		}
+	}
+}
+
+func TestDeriveDocExample(t *testing.T) {
+	st, err := loadTestStructures(t).Lookup("standard", date("2024-06-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	comps, types, err := st.Derive(money.Rupees(600000), money.ToPaisa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDerive(t, comps, map[string]string{
+		"Basic Pay":            "20000",
+		"House Rent Allowance": "10000",
+		"Employer PF":          "1800",
+		"Special Allowance":    "18200",
+	})
+	if types[2] != Employer || types[3] != Earning {
+		t.Errorf("types = %v, want employer PF and earning Special Allowance", types)
+	}
+}
+
+func TestLookupByEffectiveDate(t *testing.T) {
+	s := loadTestStructures(t)
+	tests := []struct {
+		date  string
+		basic string
+	}{
+		{"2024-04-01", "20000"},
+		{"2025-03-01", "20000"},
+		{"2025-04-01", "25000"},
+		{"2026-01-01", "25000"},
+	}
+	for _, tt := range tests {
+		st, err := s.Lookup("Standard", date(tt.date))
+		if err != nil {
+			t.Errorf("%s: %v", tt.date, err)
+			continue
+		}
+		comps, _, err := st.Derive(money.Rupees(600000), money.ToPaisa)
+		if err != nil {
+			t.Errorf("%s: %v", tt.date, err)
+			continue
+		}
+		if comps[0].Amount != money.MustParse(tt.basic) {
+			t.Errorf("%s: Basic Pay = %s, want %s", tt.date, comps[0].Amount, tt.basic)
+		}
+	}
+
+	st, _ := s.Lookup("standard", date("2025-04-01"))
+	comps, _, err := st.Derive(money.Rupees(600000), money.ToPaisa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDerive(t, comps, map[string]string{
+		"Basic Pay":            "25000",
+		"House Rent Allowance": "10000",
+		"Conveyance":           "1600",
+		"Employer PF":          "1800",
+		"Special Allowance":    "11600",
+	})
+
+	if _, err := s.Lookup("standard", date("2024-03-01")); err == nil {
+		t.Error("Lookup before the first version: want error")
+	}
+	if _, err := s.Lookup("executive", date("2024-06-01")); err == nil {
+		t.Error("Lookup of an unknown structure: want error")
+	}
+}
+
+func TestDeriveExceedsCTC(t *testing.T) {
+	st, err := loadTestStructures(t).Lookup("top-heavy", date("2024-06-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = st.Derive(money.Rupees(600000), money.ToPaisa)
+	if err == nil || !strings.Contains(err.Error(), "exceed monthly CTC 50000.00 by 10000.00") {
+		t.Errorf("Derive = %v, want components exceed monthly CTC error", err)
+	}
+}
+
+func TestComputeKeepsSheetComponents(t *testing.T) {
+	e := &Engine{Structures: loadTestStructures(t)}
+	emp := &model.Employee{
+		Name:            "Asha",
+		Month:           "June",
+		Year:            "2024",
+		AnnualCTC:       money.Rupees(600000),
+		SalaryStructure: "standard",
+		ExtraEarnings: []model.Component{
+			{Name: "Bonus", Amount: money.Rupees(5000)},
+			{Name: "Special Allowance", Amount: money.Rupees(18000)},
+		},
+	}
+	mismatches, err := e.Compute(emp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.Component{
+		{Name: "Special Allowance", Rate: money.Rupees(18200), Amount: money.Rupees(18200)},
+		{Name: "Bonus", Amount: money.Rupees(5000)},
+	}
+	if len(emp.ExtraEarnings) != len(want) {
+		t.Fatalf("ExtraEarnings = %v, want %v", emp.ExtraEarnings, want)
+	}
+	for i, c := range emp.ExtraEarnings {
+		if c != want[i] {
+			t.Errorf("ExtraEarnings[%d] = %+v, want %+v", i, c, want[i])
+		}
+	}
+	if emp.GrossEarnings != money.Rupees(53200) {
+		t.Errorf("GrossEarnings = %s, want 53200.00", emp.GrossEarnings)
+	}
+	if len(mismatches) != 1 || mismatches[0].Field != "Special Allowance" {
+		t.Errorf("mismatches = %v, want only Special Allowance", mismatches)
+	}
+}
@@ -0,0 +1,327 @@
+package payroll
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed taxdata/*.yaml
+var builtinTaxData embed.FS
+
+// Tax regimes.
+const (
+	OldRegime = "old"
+	NewRegime = "new"
+)
+
+// TaxSlab is one band of an income tax table: Rate percent on the part of
+// taxable income above the previous band, up to and including Upto (zero
+// means no upper limit).
+type TaxSlab struct {
+	Upto money.Amount `yaml:"upto"`
+	Rate string       `yaml:"rate"`
+}
+
+// Surcharge is Rate percent of tax on income Above a threshold.
+type Surcharge struct {
+	Above money.Amount `yaml:"above"`
+	Rate  string       `yaml:"rate"`
+}
+
+// Rebate is the rebate u/s 87A: tax up to Max is waived when taxable income
+// is at most IncomeUpto. With MarginalRelief, tax on income just above
+// IncomeUpto is limited to the excess income.
+type Rebate struct {
+	IncomeUpto     money.Amount `yaml:"income_upto"`
+	Max            money.Amount `yaml:"max"`
+	MarginalRelief bool         `yaml:"marginal_relief"`
+}
+
+// Regime is one tax regime of a financial year.
+type Regime struct {
+	StandardDeduction money.Amount `yaml:"standard_deduction"`
+	// Deductions allows HRA exemption, professional tax and Chapter VI-A
+	// deductions (capped at Limits, keyed by section: 80C, 80D).
+	Deductions bool                    `yaml:"deductions"`
+	Limits     map[string]money.Amount `yaml:"limits"`
+	Slabs      []TaxSlab               `yaml:"slabs"`
+	Rebate     Rebate                  `yaml:"rebate"`
+	Surcharge  []Surcharge             `yaml:"surcharge"` // ascending thresholds
+}
+
+// TaxYear holds the income tax rates of one financial year.
+type TaxYear struct {
+	FY      string             `yaml:"fy"` // e.g. "2025-26"
+	Cess    string             `yaml:"cess"`
+	Regimes map[string]*Regime `yaml:"regimes"`
+}
+
+// TaxTables holds the rates of every known financial year.
+type TaxTables struct {
+	years map[string]*TaxYear
+}
+
+// DefaultTax returns the built-in rates under taxdata/.
+func DefaultTax() *TaxTables {
+	t := &TaxTables{years: make(map[string]*TaxYear)}
+	if err := t.load(builtinTaxData, "taxdata"); err != nil {
+		panic(fmt.Sprintf("payroll: built-in tax data: %v", err))
+	}
+	return t
+}
+
+// LoadTax reads every .yaml, .yml or .json file in dir (one financial year
+// per file, in the format of taxdata/fy2025-26.yaml) over the built-in
+// rates, so that a new or revised year needs no rebuild.
+func LoadTax(dir string) (*TaxTables, error) {
+	t := DefaultTax()
+	if err := t.load(os.DirFS(dir), "."); err != nil {
+		return nil, fmt.Errorf("tax data %s: %w", dir, err)
+	}
+	return t, nil
+}
+
+func (t *TaxTables) load(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		switch strings.ToLower(path.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		var y TaxYear
+		if err := yaml.Unmarshal(data, &y); err != nil {
+			return fmt.Errorf("parse %s: %w", entry.Name(), err)
+		}
+		if err := y.validate(); err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		t.years[y.FY] = &y
+	}
+	return nil
+}
+
+func (y *TaxYear) validate() error {
+	if y.FY == "" {
+		return fmt.Errorf("missing fy")
+	}
+	if _, ok := new(big.Rat).SetString(y.Cess); !ok {
+		return fmt.Errorf("FY %s: invalid cess %q", y.FY, y.Cess)
+	}
+	for name, r := range y.Regimes {
+		if name != OldRegime && name != NewRegime {
+			return fmt.Errorf("FY %s: unknown regime %q (want %s or %s)", y.FY, name, OldRegime, NewRegime)
+		}
+		for i, s := range r.Slabs {
+			if _, ok := new(big.Rat).SetString(s.Rate); !ok {
+				return fmt.Errorf("FY %s %s regime: invalid slab rate %q", y.FY, name, s.Rate)
+			}
+			if s.Upto == 0 && i != len(r.Slabs)-1 {
+				return fmt.Errorf("FY %s %s regime: only the last slab may omit upto", y.FY, name)
+			}
+		}
+		for _, s := range r.Surcharge {
+			if _, ok := new(big.Rat).SetString(s.Rate); !ok {
+				return fmt.Errorf("FY %s %s regime: invalid surcharge rate %q", y.FY, name, s.Rate)
+			}
+		}
+	}
+	return nil
+}
+
+// FinancialYear returns the financial year ("2025-26") the pay month
+// starting at month falls in, and the number of pay months from month to
+// the end of that year (March), month included.
+func FinancialYear(month time.Time) (string, int) {
+	start := month.Year()
+	if month.Month() < time.April {
+		start--
+	}
+	remaining := int(time.March-month.Month()) + 1
+	if month.Month() >= time.April {
+		remaining += 12
+	}
+	return fmt.Sprintf("%d-%02d", start, (start+1)%100), remaining
+}
+
+// Year returns the rates of a financial year.
+func (t *TaxTables) Year(fy string) (*TaxYear, error) {
+	y, ok := t.years[fy]
+	if !ok {
+		return nil, fmt.Errorf("no income tax rates for FY %s", fy)
+	}
+	return y, nil
+}
+
+// Tax returns the annual income tax on taxable income under the named
+// regime: slab tax, less the 87A rebate, plus surcharge and cess. Taxable
+// income and tax are rounded to the nearest ten rupees (sections 288A/288B).
+func (y *TaxYear) Tax(regime string, taxable money.Amount) (money.Amount, error) {
+	r, ok := y.Regimes[regime]
+	if !ok {
+		return 0, fmt.Errorf("FY %s has no %s regime", y.FY, regime)
+	}
+	taxable = roundTen(max(taxable, 0))
+
+	tax := r.slabTax(taxable)
+	if r.Rebate.IncomeUpto > 0 {
+		if taxable <= r.Rebate.IncomeUpto {
+			tax -= money.Min(tax, r.Rebate.Max)
+		} else if r.Rebate.MarginalRelief {
+			tax = money.Min(tax, taxable-r.Rebate.IncomeUpto)
+		}
+	}
+	tax += r.surcharge(taxable, tax)
+	tax += tax.Percent(y.Cess)
+	return roundTen(tax), nil
+}
+
+func (r *Regime) slabTax(taxable money.Amount) money.Amount {
+	var tax, lower money.Amount
+	for _, s := range r.Slabs {
+		upper := s.Upto
+		if upper == 0 || upper > taxable {
+			upper = taxable
+		}
+		if upper > lower {
+			tax += (upper - lower).Percent(s.Rate)
+		}
+		if s.Upto == 0 || s.Upto >= taxable {
+			break
+		}
+		lower = s.Upto
+	}
+	return tax
+}
+
+// surcharge returns the surcharge on tax for taxable income, with marginal
+// relief: tax plus surcharge may exceed the amount payable at the threshold
+// by no more than the income above the threshold.
+func (r *Regime) surcharge(taxable, tax money.Amount) money.Amount {
+	for i := len(r.Surcharge) - 1; i >= 0; i-- {
+		s := r.Surcharge[i]
+		if taxable <= s.Above {
+			continue
+		}
+		surcharge := tax.Percent(s.Rate)
+
+		atThreshold := r.slabTax(s.Above)
+		if i > 0 {
+			atThreshold += atThreshold.Percent(r.Surcharge[i-1].Rate)
+		}
+		limit := atThreshold + (taxable - s.Above)
+		if tax+surcharge > limit {
+			surcharge = max(limit-tax, 0)
+		}
+		return surcharge
+	}
+	return 0
+}
+
+// roundTen rounds a half up to the nearest ten rupees.
+func roundTen(a money.Amount) money.Amount {
+	return (a + 500) / 1000 * 1000
+}
+
+// computeTDS projects emp's taxable income for the financial year, computes
+// the annual tax under its regime and deducts the part not yet paid spread
+// evenly over the remaining months, this one included. The projection is the
+// year-to-date gross, this month's earnings and the recurring full-month
+// earnings for every later month: Basic, HRA, Other Allowance and the extra
+// earnings with a standard rate (from the sheet or a salary structure).
+// Extra earnings without one, e.g. a Bonus or arrears, are paid once.
+func (e *Engine) computeTDS(emp *model.Employee) ([]Mismatch, error) {
+	month, ok := emp.PeriodStart()
+	if !ok {
+		return nil, fmt.Errorf("cannot compute income tax: unknown pay month %q %q", emp.Month, emp.Year)
+	}
+	fy, remaining := FinancialYear(month)
+	year, err := e.Tax.Year(fy)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []Mismatch
+	if e.Declarations != nil {
+		fyStart := month.AddDate(0, remaining-12, 0)
+		mismatches = append(mismatches, e.applyDeclaration(emp, fy, fyStart, month)...)
+	}
+	regime := strings.ToLower(strings.TrimSpace(emp.TaxRegime))
+	if regime == "" {
+		regime = NewRegime
+	}
+	r, ok := year.Regimes[regime]
+	if !ok {
+		return nil, fmt.Errorf("FY %s has no %s regime", fy, regime)
+	}
+
+	var current money.Amount
+	for _, c := range emp.Earnings() {
+		current += c.Amount
+	}
+	recurring := fullMonth(emp.BasicPayRate, emp.BasicPayAmount) +
+		fullMonth(emp.HRARate, emp.HRAAmount) +
+		fullMonth(emp.OtherAllowanceRate, emp.OtherAllowanceAmount)
+	for _, c := range emp.ExtraEarnings {
+		recurring += c.Rate
+	}
+	later := int64(remaining - 1)
+	gross := emp.YTD.Gross + current + recurring*money.Amount(later)
+
+	taxable := gross - r.StandardDeduction
+	if r.Deductions {
+		pt := emp.YTD.ProfessionalTax + emp.ProfessionalTax*money.Amount(remaining)
+		pf := emp.YTD.PF + emp.PF*money.Amount(remaining)
+		taxable -= emp.HRAExemption + pt
+		taxable -= limit(r.Limits, "80C", emp.Declared80C+pf)
+		taxable -= limit(r.Limits, "80D", emp.Declared80D)
+	}
+
+	annual, err := year.Tax(regime, taxable)
+	if err != nil {
+		return nil, err
+	}
+	due := max(annual-emp.YTD.IncomeTax, 0)
+	tds := money.ToRupee.Apply(due.MulDiv(1, int64(remaining)))
+
+	if emp.IncomeTax != 0 && emp.IncomeTax != tds {
+		mismatches = append(mismatches, amountMismatch("Income Tax", emp.IncomeTax, tds))
+	}
+	emp.IncomeTax = tds
+	emp.HasIncomeTax = annual > 0
+	return mismatches, nil
+}
+
+// fullMonth returns the standard rate of an earning, or this month's amount
+// when the sheet has no rate.
+func fullMonth(rate, amount money.Amount) money.Amount {
+	if rate > 0 {
+		return rate
+	}
+	return amount
+}
+
+// limit caps a deduction at the regime's limit for section, if it has one.
+func limit(limits map[string]money.Amount, section string, amount money.Amount) money.Amount {
+	if l, ok := limits[section]; ok {
+		return money.Min(amount, l)
+	}
+	return amount
+}
@@ -0,0 +1,46 @@
+package payroll
+
+import (
+	"testing"
+
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+)
+
+func TestTDSMidYearBonus(t *testing.T) {
+	e := &Engine{Tax: DefaultTax()}
+	salary := func(month string, ytdGross, ytdTax money.Amount) model.Employee {
+		return model.Employee{
+			Month: month, Year: "2024",
+			BasicPayRate: money.Rupees(80000), BasicPayAmount: money.Rupees(80000),
+			ExtraEarnings: []model.Component{
+				{Name: "Special Allowance", Rate: money.Rupees(20000), Amount: money.Rupees(20000)},
+			},
+			YTD: model.YTD{Gross: ytdGross, IncomeTax: ytdTax},
+		}
+	}
+
+	// A 2,00,000 bonus in October, six months before the year ends, is paid
+	// once: 6,00,000 so far + 3,00,000 this month + 5 x 1,00,000 is a
+	// projected 14,00,000, 13,25,000 after the standard deduction. The new
+	// regime's tax on that is 1,05,000 and 1,09,200 with cess, a sixth of
+	// which is due now.
+	oct := salary("October", money.Rupees(600000), 0)
+	oct.ExtraEarnings = append(oct.ExtraEarnings, model.Component{Name: "Bonus", Amount: money.Rupees(200000)})
+	if _, err := e.computeTDS(&oct); err != nil {
+		t.Fatal(err)
+	}
+	if want := money.Rupees(18200); oct.IncomeTax != want {
+		t.Errorf("October TDS = %s, want %s", oct.IncomeTax, want)
+	}
+
+	// November projects the same income, so the rest of the tax is spread
+	// evenly.
+	nov := salary("November", money.Rupees(900000), oct.IncomeTax)
+	if _, err := e.computeTDS(&nov); err != nil {
+		t.Fatal(err)
+	}
+	if want := money.Rupees(18200); nov.IncomeTax != want {
+		t.Errorf("November TDS = %s, want %s", nov.IncomeTax, want)
+	}
+}
@@ -0,0 +1,98 @@
+package reader
+
+import (
+	"strconv"
+	"strings"
+)
+
+// componentColumn is an extra earning or deduction resolved to its amount
+// column and, when present, its standard rate column.
+type componentColumn struct {
+	kind   string
+	amount column
+	rate   *column
+}
+
+// resolveComponentColumns finds the columns of the configured components and,
+// when the mapping asks for it, turns every remaining numeric column into a
+// component named after its header. used holds the header indices already
+// claimed by fields; it is updated with the columns claimed here.
+func resolveComponentColumns(rows [][]string, headerMap map[string]int, mapping *Mapping, used map[int]bool) []componentColumn {
+	header := rows[0]
+	lookup := func(names []string) (int, bool) {
+		for _, name := range names {
+			if idx, ok := headerMap[strings.ToLower(strings.TrimSpace(name))]; ok && !used[idx] {
+				return idx, true
+			}
+		}
+		return 0, false
+	}
+
+	var comps []componentColumn
+	for _, c := range mapping.resolveComponents() {
+		idx, ok := lookup(c.Columns)
+		if !ok {
+			continue
+		}
+		used[idx] = true
+		cc := componentColumn{kind: c.Type, amount: newColumn(componentField(c.Name), idx, header[idx])}
+		if ridx, ok := lookup(c.RateColumns); ok {
+			used[ridx] = true
+			rate := newColumn(componentField(c.Name), ridx, header[ridx])
+			cc.rate = &rate
+		}
+		comps = append(comps, cc)
+	}
+
+	kind := mapping.unmapped()
+	if kind == Ignore {
+		return comps
+	}
+	for idx, h := range header {
+		name := strings.TrimSpace(h)
+		if used[idx] || name == "" || !numericColumn(rows[1:], idx) {
+			continue
+		}
+		// "X Rate" belongs to column "X" when that column exists.
+		lower := strings.ToLower(name)
+		if base, ok := strings.CutSuffix(lower, " rate"); ok {
+			if bidx, ok := headerMap[base]; ok && !used[bidx] {
+				continue
+			}
+		}
+		used[idx] = true
+		cc := componentColumn{kind: kind, amount: newColumn(componentField(name), idx, h)}
+		if ridx, ok := headerMap[lower+" rate"]; ok && !used[ridx] {
+			used[ridx] = true
+			rate := newColumn(componentField(name), ridx, header[ridx])
+			cc.rate = &rate
+		}
+		comps = append(comps, cc)
+	}
+	return comps
+}
+
+// componentField is the pseudo field a component column is parsed with.
+func componentField(name string) field {
+	return field{name: name, transforms: []string{"trim", "strip_commas"}}
+}
+
+// numericColumn reports whether column idx has at least one value and every
+// non-empty value in it is a number.
+func numericColumn(rows [][]string, idx int) bool {
+	found := false
+	for _, row := range rows {
+		if idx >= len(row) {
+			continue
+		}
+		v := strings.TrimSpace(strings.ReplaceAll(row[idx], ",", ""))
+		if v == "" {
+			continue
+		}
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return false
+		}
+		found = true
+	}
+	return found
+}
@@ -0,0 +1,279 @@
+package reader
+
+import (
+	"fmt"
+	"log"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// column is a field resolved to its position in the header row.
+type column struct {
+	field
+	index  int
+	letter string
+	header string
+}
+
+// parseTable maps the rows of a Table onto employees as described by opts.
+// It is shared by every Source so that column aliases live in one place.
+// Totals are read as supplied; payroll.Engine computes and reconciles them.
+//
+// Problems in individual rows (unparseable numbers, missing names, malformed
+// identifiers, duplicates) do not stop parsing; they are returned as issues
+// so the caller can decide whether to abort.
+func parseTable(table *Table, opts Options) ([]model.Employee, []Issue, error) {
+	mapping := opts.Mapping
+	rows := table.Rows
+	if len(rows) < 2 {
+		return nil, nil, fmt.Errorf("%s is empty or missing header", table.Sheet)
+	}
+
+	// Map headers to indices with normalizer
+	headerMap := make(map[string]int)
+	for i, cell := range rows[0] {
+		normalized := strings.ToLower(strings.TrimSpace(cell))
+		headerMap[normalized] = i
+	}
+
+	// Resolve each field to the first of its aliases present in the header.
+	// Every alias present counts as used so it is not mistaken for a component.
+	var columns []column
+	byField := make(map[string]column)
+	used := make(map[int]bool)
+	for _, f := range mapping.resolve() {
+		for _, name := range f.columns {
+			idx, ok := headerMap[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				continue
+			}
+			used[idx] = true
+			if _, resolved := byField[f.name]; resolved {
+				continue
+			}
+			col := newColumn(f, idx, rows[0][idx])
+			columns = append(columns, col)
+			byField[f.name] = col
+		}
+	}
+	components := resolveComponentColumns(rows, headerMap, mapping, used)
+	for _, cc := range components {
+		byField[cc.amount.name] = cc.amount
+	}
+	nameCol, hasName := byField["Name"]
+	_, hasIncomeTax := byField["IncomeTax"]
+
+	var issues []Issue
+	if !hasName {
+		issues = append(issues, Issue{Sheet: table.Sheet, Row: 1, Message: "no employee name column found"})
+	}
+
+	// Internal helper to get value safely
+	getVal := func(row []string, col column) string {
+		if col.index < len(row) {
+			return col.apply(row[col.index])
+		}
+		return ""
+	}
+
+	var employees []model.Employee
+	seen := make(map[string]int)
+
+	for i, row := range rows[1:] {
+		if isBlank(row) {
+			continue
+		}
+		rowIssues := &rowIssues{sheet: table.Sheet, row: i + 2, columns: byField}
+
+		// Rows without a name cannot be paid; skip them but say so.
+		if !hasName || getVal(row, nameCol) == "" {
+			if hasName {
+				rowIssues.add("Name", "", "missing employee name, row skipped")
+			}
+			issues = append(issues, rowIssues.issues...)
+			continue
+		}
+
+		emp := model.Employee{HasIncomeTax: hasIncomeTax}
+		for _, col := range columns {
+			val := getVal(row, col)
+			if col.text != nil {
+				*col.text(&emp) = val
+				continue
+			}
+			if col.number != nil {
+				*col.number(&emp) = parseNumber(row, col, rowIssues)
+				continue
+			}
+			if col.date != nil {
+				*col.date(&emp) = parseDateCell(row, col, rowIssues)
+				continue
+			}
+			if col.flag != nil {
+				*col.flag(&emp) = parseFlag(row, col, rowIssues)
+				continue
+			}
+			*col.amount(&emp) = parseAmount(row, col, rowIssues)
+		}
+
+		for _, cc := range components {
+			amt := parseAmount(row, cc.amount, rowIssues)
+			var rate money.Amount
+			if cc.rate != nil {
+				rate = parseAmount(row, *cc.rate, rowIssues)
+			}
+			if amt == 0 && rate == 0 {
+				continue
+			}
+			comp := model.Component{Name: cc.amount.name, Rate: rate, Amount: amt}
+			if cc.kind == Deduction {
+				emp.ExtraDeductions = append(emp.ExtraDeductions, comp)
+			} else {
+				emp.ExtraEarnings = append(emp.ExtraEarnings, comp)
+			}
+		}
+
+		if emp.Month == "" {
+			emp.Month = opts.Month
+		}
+		if emp.Year == "" {
+			emp.Year = opts.Year
+		}
+
+		validateEmployee(&emp, rowIssues)
+		key := employeeKey(&emp)
+		if first, dup := seen[key]; dup {
+			rowIssues.add("Name", emp.Name, fmt.Sprintf("duplicate of row %d for %s %s", first, emp.Month, emp.Year))
+		} else {
+			seen[key] = rowIssues.row
+		}
+		issues = append(issues, rowIssues.issues...)
+
+		employees = append(employees, emp)
+	}
+
+	// Debug log if no employees found but headers existed
+	if len(employees) == 0 {
+		log.Println("Warning: No valid employee rows found. Check column headers.")
+		log.Printf("Found headers: %v", headerMap)
+	}
+
+	return employees, issues, nil
+}
+
+// newColumn resolves f to header column idx.
+func newColumn(f field, idx int, header string) column {
+	letter, _ := excelize.ColumnNumberToName(idx + 1)
+	return column{field: f, index: idx, letter: letter, header: header}
+}
+
+// parseAmount parses the numeric cell of col in row. Empty cells are zero;
+// anything else that is not a number is reported and treated as zero.
+func parseAmount(row []string, col column, r *rowIssues) money.Amount {
+	if col.index >= len(row) {
+		return 0
+	}
+	amt, err := money.Parse(col.apply(row[col.index]))
+	if err != nil {
+		r.add(col.name, row[col.index], "not a number")
+		return 0
+	}
+	return amt
+}
+
+// parseNumber parses a plain decimal cell of col in row, e.g. "2" or "1.5".
+func parseNumber(row []string, col column, r *rowIssues) float64 {
+	if col.index >= len(row) {
+		return 0
+	}
+	val := col.apply(row[col.index])
+	if val == "" {
+		return 0
+	}
+	n, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		r.add(col.name, row[col.index], "not a number")
+		return 0
+	}
+	return n
+}
+
+// dateLayouts are the date formats accepted in the sheet, tried in order.
+// "01-02-06" is how excelize renders cells with Excel's default date format.
+var dateLayouts = []string{
+	"2006-01-02", "02-01-2006", "02/01/2006", "2-1-2006", "2/1/2006",
+	"02-Jan-2006", "2-Jan-2006", "02 Jan 2006", "2 Jan 2006", "January 2, 2006",
+	"01-02-06",
+}
+
+// parseDate reads a date in any of dateLayouts, or an Excel serial day number.
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(s, 64); err == nil && serial > 0 {
+		return excelize.ExcelDateToTime(serial, false)
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+}
+
+// parseDateCell parses the date in the cell of col in row.
+func parseDateCell(row []string, col column, r *rowIssues) time.Time {
+	if col.index >= len(row) {
+		return time.Time{}
+	}
+	val := col.apply(row[col.index])
+	if val == "" {
+		return time.Time{}
+	}
+	t, err := parseDate(val)
+	if err != nil {
+		r.add(col.name, row[col.index], "not a date (use YYYY-MM-DD)")
+		return time.Time{}
+	}
+	return t
+}
+
+// parseFlag reads a yes/no cell in any case, whatever transforms the
+// mapping gives the field; empty means no.
+func parseFlag(row []string, col column, r *rowIssues) bool {
+	if col.index >= len(row) {
+		return false
+	}
+	val := strings.TrimSpace(col.apply(row[col.index]))
+	switch {
+	case val == "" || equalsAny(val, "no", "n", "false", "0"):
+		return false
+	case equalsAny(val, "yes", "y", "true", "1"):
+		return true
+	}
+	r.add(col.name, row[col.index], "expected yes or no")
+	return false
+}
+
+// equalsAny reports whether s equals one of words, ignoring case.
+func equalsAny(s string, words ...string) bool {
+	for _, w := range words {
+		if strings.EqualFold(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBlank reports whether every cell in row is empty.
+func isBlank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package reader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFlag(t *testing.T) {
+	tests := []struct {
+		transforms []string
+		cell       string
+		want       bool
+		wantIssue  bool
+	}{
+		{[]string{"trim", "lower"}, " Yes ", true, false},
+		{nil, "Yes", true, false},
+		{[]string{"trim"}, "YES", true, false},
+		{[]string{"trim", "upper"}, "y", true, false},
+		{nil, "True", true, false},
+		{nil, " No", false, false},
+		{nil, "", false, false},
+		{nil, "maybe", false, true},
+	}
+	for _, tt := range tests {
+		col := column{field: field{name: "ESICovered", transforms: tt.transforms}}
+		r := &rowIssues{row: 2}
+		if got := parseFlag([]string{tt.cell}, col, r); got != tt.want {
+			t.Errorf("parseFlag(%q) with %v = %v, want %v", tt.cell, tt.transforms, got, tt.want)
+		}
+		if got := len(r.issues) > 0; got != tt.wantIssue {
+			t.Errorf("parseFlag(%q) with %v: issues %v, want issue %v", tt.cell, tt.transforms, r.issues, tt.wantIssue)
+		}
+	}
+}
+
+func TestPayPeriod(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       [][]string
+		opts       Options
+		month      string
+		year       string
+		issueField string
+	}{
+		{"from the sheet", [][]string{{"Month", "Year", "Name", "Email"}, {"May", "2024", "Asha", "asha@a.example"}},
+			Options{Month: "June", Year: "2025"}, "May", "2024", ""},
+		{"from the run", [][]string{{"Name", "Email"}, {"Asha", "asha@a.example"}},
+			Options{Month: "June", Year: "2025"}, "June", "2025", ""},
+		{"empty cell", [][]string{{"Month", "Year", "Name", "Email"}, {"", "2024", "Asha", "asha@a.example"}},
+			Options{Month: "June"}, "June", "2024", ""},
+		{"missing", [][]string{{"Name", "Email"}, {"Asha", "asha@a.example"}},
+			Options{}, "", "", "Month"},
+		{"unknown", [][]string{{"Month", "Year", "Name", "Email"}, {"Mayo", "2024", "Asha", "asha@a.example"}},
+			Options{}, "Mayo", "2024", "Month"},
+	}
+	for _, tt := range tests {
+		employees, issues, err := parseTable(&Table{Sheet: "Sheet1", Rows: tt.rows}, tt.opts)
+		if err != nil || len(employees) != 1 {
+			t.Fatalf("%s: %d employees, %v", tt.name, len(employees), err)
+		}
+		if emp := employees[0]; emp.Month != tt.month || emp.Year != tt.year {
+			t.Errorf("%s: period %q %q, want %q %q", tt.name, emp.Month, emp.Year, tt.month, tt.year)
+		}
+		var fields []string
+		for _, issue := range issues {
+			fields = append(fields, issue.Field)
+		}
+		var want []string
+		if tt.issueField != "" {
+			want = []string{tt.issueField}
+		}
+		if !reflect.DeepEqual(fields, want) {
+			t.Errorf("%s: issues on %q, want %q", tt.name, fields, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package reader
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Issue is a problem found in the input, located by sheet, row and column so
+// that finance can fix the sheet directly.
+type Issue struct {
+	Sheet   string `json:"sheet"`
+	Row     int    `json:"row"`              // 1-based, header is row 1
+	Column  string `json:"column,omitempty"` // spreadsheet column letter, e.g. "F"
+	Header  string `json:"header,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	loc := fmt.Sprintf("%s row %d", i.Sheet, i.Row)
+	if i.Column != "" {
+		loc += fmt.Sprintf(" col %s (%s)", i.Column, i.Header)
+	}
+	if i.Value != "" {
+		return fmt.Sprintf("%s: %s: %q", loc, i.Message, i.Value)
+	}
+	return fmt.Sprintf("%s: %s", loc, i.Message)
+}
+
+// WriteIssuesJSON writes issues as a JSON array.
+func WriteIssuesJSON(w io.Writer, issues []Issue) error {
+	if issues == nil {
+		issues = []Issue{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
+
+// WriteIssuesCSV writes issues as CSV with a header row.
+func WriteIssuesCSV(w io.Writer, issues []Issue) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"Sheet", "Row", "Column", "Header", "Field", "Value", "Message"}); err != nil {
+		return err
+	}
+	for _, i := range issues {
+		rec := []string{i.Sheet, strconv.Itoa(i.Row), i.Column, i.Header, i.Field, i.Value, i.Message}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteReport writes issues to base+".json" and base+".csv".
+func WriteReport(base string, issues []Issue) error {
+	for ext, write := range map[string]func(io.Writer, []Issue) error{
+		".json": WriteIssuesJSON,
+		".csv":  WriteIssuesCSV,
+	} {
+		f, err := os.Create(base + ext)
+		if err != nil {
+			return err
+		}
+		if err := write(f, issues); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
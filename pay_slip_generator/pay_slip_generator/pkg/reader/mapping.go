@@ -0,0 +1,289 @@
+package reader
+
+import (
+	"fmt"
+	"os"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mapping describes which sheet columns feed which model.Employee fields.
+// It is loaded from a YAML or JSON file, e.g.
+//
+//	fields:
+//	  Name:
+//	    columns: ["Emp Name", "Employee"]
+//	    transforms: [trim]
+//	  PAN:
+//	    columns: ["PAN No"]
+//	    transforms: [trim, upper]
+//
+// Fields that are not listed keep their built-in column aliases and transforms.
+//
+// Components declare extra earnings and deductions by name. Any other numeric
+// column is treated according to Unmapped: "ignore" (the default), "earning"
+// or "deduction", with a matching "<header> Rate" column used as its rate.
+type Mapping struct {
+	Fields     map[string]FieldMapping `yaml:"fields"`
+	Components []ComponentMapping      `yaml:"components"`
+	Unmapped   string                  `yaml:"unmapped"`
+}
+
+// FieldMapping lists the header aliases for one field, tried in order, and
+// the transforms applied to the cell value before it is stored.
+type FieldMapping struct {
+	Columns    []string `yaml:"columns"`
+	Transforms []string `yaml:"transforms"`
+}
+
+// Component types accepted in a mapping file.
+const (
+	Earning   = "earning"
+	Deduction = "deduction"
+	Ignore    = "ignore"
+)
+
+// ComponentMapping declares an extra earning or deduction read from the sheet.
+// Columns default to the component name.
+type ComponentMapping struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`
+	Columns     []string `yaml:"columns"`
+	RateColumns []string `yaml:"rate_columns"`
+}
+
+// builtinComponents are recognised without a mapping file.
+var builtinComponents = []ComponentMapping{
+	{Name: "Special Allowance", Type: Earning, Columns: []string{"Special Allowance", "Spl Allowance"}, RateColumns: []string{"Special Allowance Rate"}},
+	{Name: "Conveyance", Type: Earning, Columns: []string{"Conveyance", "Conveyance Allowance"}, RateColumns: []string{"Conveyance Rate"}},
+	{Name: "Leave Travel Allowance", Type: Earning, Columns: []string{"LTA", "Leave Travel Allowance"}, RateColumns: []string{"LTA Rate"}},
+	{Name: "Bonus", Type: Earning, Columns: []string{"Bonus"}},
+	{Name: "ESI", Type: Deduction, Columns: []string{"ESI", "ESIC"}},
+	{Name: "Loan EMI", Type: Deduction, Columns: []string{"Loan EMI", "Loan Recovery"}},
+	{Name: "Canteen", Type: Deduction, Columns: []string{"Canteen", "Canteen Charges"}},
+}
+
+// transforms are the per-field value clean-ups a mapping file may ask for.
+var transforms = map[string]func(string) string{
+	"trim":         strings.TrimSpace,
+	"upper":        strings.ToUpper,
+	"lower":        strings.ToLower,
+	"strip_commas": func(s string) string { return strings.ReplaceAll(s, ",", "") },
+}
+
+// field binds a mapping key to a model.Employee field. Exactly one of text,
+// amount, number, date and flag is set.
+type field struct {
+	name       string
+	columns    []string
+	transforms []string
+	text       func(*model.Employee) *string
+	amount     func(*model.Employee) *money.Amount
+	number     func(*model.Employee) *float64
+	date       func(*model.Employee) *time.Time
+	flag       func(*model.Employee) *bool
+}
+
+func textField(name string, columns []string, ptr func(*model.Employee) *string) field {
+	return field{name: name, columns: columns, text: ptr}
+}
+
+func amountField(name string, columns []string, ptr func(*model.Employee) *money.Amount) field {
+	return field{name: name, columns: columns, transforms: []string{"strip_commas"}, amount: ptr}
+}
+
+func numberField(name string, columns []string, ptr func(*model.Employee) *float64) field {
+	return field{name: name, columns: columns, transforms: []string{"trim"}, number: ptr}
+}
+
+func dateField(name string, columns []string, ptr func(*model.Employee) *time.Time) field {
+	return field{name: name, columns: columns, transforms: []string{"trim"}, date: ptr}
+}
+
+func flagField(name string, columns []string, ptr func(*model.Employee) *bool) field {
+	return field{name: name, columns: columns, transforms: []string{"trim", "lower"}, flag: ptr}
+}
+
+// fields are the built-in column aliases, used when no mapping file overrides them.
+var fields = []field{
+	textField("Month", []string{"Month"}, func(e *model.Employee) *string { return &e.Month }),
+	textField("Year", []string{"Year"}, func(e *model.Employee) *string { return &e.Year }),
+	textField("Name", []string{"Emp Name", "Name", "Employee Name", "Employee"}, func(e *model.Employee) *string { return &e.Name }),
+	textField("Designation", []string{"Designation", "Role", "Position"}, func(e *model.Employee) *string { return &e.Designation }),
+	textField("Email", []string{"Email", "Email Address", "E-mail"}, func(e *model.Employee) *string { return &e.Email }),
+	textField("BankAcNo", []string{"Bank Ac No", "Bank Account", "Account No"}, func(e *model.Employee) *string { return &e.BankAcNo }),
+	textField("IFSC", []string{"IFSC", "IFSC Code", "Bank IFSC"}, func(e *model.Employee) *string { return &e.IFSC }),
+	dateField("DOJ", []string{"DOJ", "Date of Joining", "Joining Date"}, func(e *model.Employee) *time.Time { return &e.DOJ }),
+	dateField("DOB", []string{"DOB", "Date of Birth", "Birth Date"}, func(e *model.Employee) *time.Time { return &e.DOB }),
+	dateField("ExitDate", []string{"Date of Exit", "Exit Date", "DOE", "Last Working Day", "LWD"}, func(e *model.Employee) *time.Time { return &e.ExitDate }),
+	textField("Gender", []string{"Gender", "Sex"}, func(e *model.Employee) *string { return &e.Gender }),
+	textField("Entity", []string{"Entity", "Legal Entity", "Company"}, func(e *model.Employee) *string { return &e.Entity }),
+	textField("PAN", []string{"PAN", "PAN Number"}, func(e *model.Employee) *string { return &e.PAN }),
+	textField("State", []string{"State", "Work State", "Work Location", "Location"}, func(e *model.Employee) *string { return &e.State }),
+	textField("UAN", []string{"UAN", "UAN Number", "Universal Account Number"}, func(e *model.Employee) *string { return &e.UAN }),
+	textField("PFNo", []string{"PF No", "PF Number", "PF Account No", "PF Account"}, func(e *model.Employee) *string { return &e.PFNo }),
+	flagField("PFOnFullBasic", []string{"PF On Full Basic", "PF Full Basic"}, func(e *model.Employee) *bool { return &e.PFOnFullBasic }),
+	textField("ESINo", []string{"ESI IP No", "ESI No", "ESI Number", "IP Number"}, func(e *model.Employee) *string { return &e.ESINo }),
+	flagField("ESICovered", []string{"ESI Covered"}, func(e *model.Employee) *bool { return &e.ESICovered }),
+	numberField("VPFPercent", []string{"VPF %", "VPF Percent", "VPF Rate"}, func(e *model.Employee) *float64 { return &e.VPFPercent }),
+
+	// Salary structure
+	amountField("AnnualCTC", []string{"Annual CTC", "CTC"}, func(e *model.Employee) *money.Amount { return &e.AnnualCTC }),
+	textField("SalaryStructure", []string{"Salary Structure", "Structure"}, func(e *model.Employee) *string { return &e.SalaryStructure }),
+
+	// Income tax
+	textField("TaxRegime", []string{"Tax Regime", "Regime"}, func(e *model.Employee) *string { return &e.TaxRegime }),
+	amountField("Declared80C", []string{"80C", "80C Declaration", "Declared 80C"}, func(e *model.Employee) *money.Amount { return &e.Declared80C }),
+	amountField("Declared80D", []string{"80D", "80D Declaration", "Declared 80D"}, func(e *model.Employee) *money.Amount { return &e.Declared80D }),
+	amountField("HRAExemption", []string{"HRA Exemption"}, func(e *model.Employee) *money.Amount { return &e.HRAExemption }),
+	amountField("YTDGross", []string{"YTD Gross", "YTD Gross Earnings"}, func(e *model.Employee) *money.Amount { return &e.YTD.Gross }),
+	amountField("YTDPF", []string{"YTD PF"}, func(e *model.Employee) *money.Amount { return &e.YTD.PF }),
+	amountField("YTDProfessionalTax", []string{"YTD PT", "YTD Professional Tax"}, func(e *model.Employee) *money.Amount { return &e.YTD.ProfessionalTax }),
+	amountField("YTDIncomeTax", []string{"YTD TDS", "YTD Income Tax", "TDS Deducted"}, func(e *model.Employee) *money.Amount { return &e.YTD.IncomeTax }),
+
+	// Attendance
+	numberField("StandardDays", []string{"Standard Days", "Std Days", "Total Days"}, func(e *model.Employee) *float64 { return &e.StandardDays }),
+	numberField("PayableDays", []string{"Payable Days", "Paid Days"}, func(e *model.Employee) *float64 { return &e.PayableDays }),
+	numberField("LOPDays", []string{"Loss of Pay Days", "LOP Days", "LOP", "Absent"}, func(e *model.Employee) *float64 { return &e.LOPDays }),
+
+	// Earnings
+	amountField("BasicPayRate", []string{"Basic Pay Rate", "Basic Rate"}, func(e *model.Employee) *money.Amount { return &e.BasicPayRate }),
+	amountField("BasicPayAmount", []string{"Basic Pay", "Basic Pay Amount", "Basic"}, func(e *model.Employee) *money.Amount { return &e.BasicPayAmount }),
+	amountField("HRARate", []string{"HRA Rate"}, func(e *model.Employee) *money.Amount { return &e.HRARate }),
+	amountField("HRAAmount", []string{"HRA", "House Rent Allowance"}, func(e *model.Employee) *money.Amount { return &e.HRAAmount }),
+	amountField("OtherAllowanceRate", []string{"Other Allowance Rate", "Other Allw Rate"}, func(e *model.Employee) *money.Amount { return &e.OtherAllowanceRate }),
+	amountField("OtherAllowanceAmount", []string{"Other Allowance", "Other Allowance Amount", "Other Allw"}, func(e *model.Employee) *money.Amount { return &e.OtherAllowanceAmount }),
+
+	// Deductions
+	amountField("ProfessionalTax", []string{"Professional Tax", "Prof Tax", "PT"}, func(e *model.Employee) *money.Amount { return &e.ProfessionalTax }),
+	amountField("PF", []string{"PF", "Provident Fund"}, func(e *model.Employee) *money.Amount { return &e.PF }),
+	amountField("IncomeTax", []string{"Income Tax", "TDS", "Tax"}, func(e *model.Employee) *money.Amount { return &e.IncomeTax }),
+
+	// Totals
+	amountField("GrossEarnings", []string{"Gross Earnings", "Gross Pay", "Total Earnings"}, func(e *model.Employee) *money.Amount { return &e.GrossEarnings }),
+	amountField("TotalDeductions", []string{"Total Deductions", "Total Ded"}, func(e *model.Employee) *money.Amount { return &e.TotalDeductions }),
+	amountField("NetPay", []string{"Net Pay", "Net Salary"}, func(e *model.Employee) *money.Amount { return &e.NetPay }),
+}
+
+// DefaultMapping returns the built-in column aliases as a Mapping, which is
+// a convenient starting point for writing a mapping file.
+func DefaultMapping() *Mapping {
+	m := &Mapping{Fields: make(map[string]FieldMapping, len(fields)), Unmapped: Ignore}
+	for _, f := range fields {
+		m.Fields[f.name] = FieldMapping{Columns: f.columns, Transforms: f.transforms}
+	}
+	m.Components = append(m.Components, builtinComponents...)
+	return m
+}
+
+// LoadMapping reads a YAML or JSON mapping file and checks that every field
+// and transform it names is known.
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Mapping
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse mapping %s: %w", path, err)
+	}
+
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.name] = true
+	}
+	for name, fm := range m.Fields {
+		if !known[name] {
+			return nil, fmt.Errorf("mapping %s: unknown field %q", path, name)
+		}
+		for _, t := range fm.Transforms {
+			if _, ok := transforms[t]; !ok {
+				return nil, fmt.Errorf("mapping %s: field %s: unknown transform %q", path, name, t)
+			}
+		}
+	}
+	for i, c := range m.Components {
+		if strings.TrimSpace(c.Name) == "" {
+			return nil, fmt.Errorf("mapping %s: component %d has no name", path, i+1)
+		}
+		if c.Type != Earning && c.Type != Deduction {
+			return nil, fmt.Errorf("mapping %s: component %s: type must be %q or %q", path, c.Name, Earning, Deduction)
+		}
+	}
+	switch m.Unmapped {
+	case "", Ignore, Earning, Deduction:
+	default:
+		return nil, fmt.Errorf("mapping %s: unmapped must be %q, %q or %q", path, Ignore, Earning, Deduction)
+	}
+	return &m, nil
+}
+
+// resolve returns the built-in fields with any overrides from m applied.
+func (m *Mapping) resolve() []field {
+	resolved := make([]field, len(fields))
+	copy(resolved, fields)
+	if m == nil {
+		return resolved
+	}
+	for i, f := range resolved {
+		fm, ok := m.Fields[f.name]
+		if !ok {
+			continue
+		}
+		if fm.Columns != nil {
+			resolved[i].columns = fm.Columns
+		}
+		if fm.Transforms != nil {
+			resolved[i].transforms = fm.Transforms
+		}
+	}
+	return resolved
+}
+
+// resolveComponents returns the built-in components, with components from m
+// replacing built-ins of the same name and the rest appended in file order.
+func (m *Mapping) resolveComponents() []ComponentMapping {
+	resolved := make([]ComponentMapping, len(builtinComponents))
+	copy(resolved, builtinComponents)
+	if m == nil {
+		return resolved
+	}
+	for _, c := range m.Components {
+		if len(c.Columns) == 0 {
+			c.Columns = []string{c.Name}
+		}
+		replaced := false
+		for i := range resolved {
+			if strings.EqualFold(resolved[i].Name, c.Name) {
+				resolved[i] = c
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			resolved = append(resolved, c)
+		}
+	}
+	return resolved
+}
+
+// unmapped returns how numeric columns that match no field or component are treated.
+func (m *Mapping) unmapped() string {
+	if m == nil || m.Unmapped == "" {
+		return Ignore
+	}
+	return m.Unmapped
+}
+
+// apply runs the field's transforms over a raw cell value.
+func (f field) apply(v string) string {
+	for _, name := range f.transforms {
+		v = transforms[name](v)
+	}
+	return v
+}
@@ -0,0 +1,82 @@
+package reader
+
+import (
+	"fmt"
+	"path/filepath"
+	"pay_slip_generator/pkg/model"
+	"sort"
+	"strings"
+)
+
+// Table is the raw content of one input sheet: a header row followed by data rows.
+type Table struct {
+	Sheet string
+	Rows  [][]string
+}
+
+// Source loads an input file into a Table. A Source only deals with the file
+// format; turning the rows into employees is shared by every format.
+type Source interface {
+	Load(path string) (*Table, error)
+}
+
+var sources = make(map[string]Source)
+
+// Register makes src available for files with the given extension (e.g. ".csv").
+// Registering the same extension twice replaces the previous Source.
+func Register(ext string, src Source) {
+	sources[normalizeExt(ext)] = src
+}
+
+// SourceFor returns the Source registered for the extension of path.
+func SourceFor(path string) (Source, error) {
+	ext := normalizeExt(filepath.Ext(path))
+	src, ok := sources[ext]
+	if !ok {
+		return nil, fmt.Errorf("unsupported input format %q (supported: %s)", ext, strings.Join(Formats(), ", "))
+	}
+	return src, nil
+}
+
+// Formats lists the registered file extensions.
+func Formats() []string {
+	exts := make([]string, 0, len(sources))
+	for ext := range sources {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// Options control how Read interprets the input.
+type Options struct {
+	Mapping *Mapping // nil uses the built-in column aliases
+
+	// Month and Year are the pay period of rows whose Month or Year cell is
+	// missing or empty; rows without either are reported as issues.
+	Month string
+	Year  string
+}
+
+// Read loads the employees in path using the Source registered for its
+// extension. Rows with problems are reported as issues rather than failing
+// the whole read; see parseTable.
+func Read(path string, opts Options) ([]model.Employee, []Issue, error) {
+	src, err := SourceFor(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	table, err := src.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseTable(table, opts)
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,122 @@
+package reader
+
+import (
+	"net/mail"
+	"pay_slip_generator/pkg/model"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	panPattern  = regexp.MustCompile(`^[A-Z]{5}[0-9]{4}[A-Z]$`)
+	uanPattern  = regexp.MustCompile(`^[0-9]{12}$`)
+	ifscPattern = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+	esiPattern  = regexp.MustCompile(`^[0-9]{10}$`)
+)
+
+// rowIssues collects the issues of one data row, resolving field names to
+// the column they were read from.
+type rowIssues struct {
+	sheet   string
+	row     int
+	columns map[string]column
+	issues  []Issue
+}
+
+func (r *rowIssues) add(fieldName, value, message string) {
+	issue := Issue{Sheet: r.sheet, Row: r.row, Field: fieldName, Value: value, Message: message}
+	if col, ok := r.columns[fieldName]; ok {
+		issue.Column = col.letter
+		issue.Header = col.header
+	}
+	r.issues = append(r.issues, issue)
+}
+
+// validateEmployee checks the values of a parsed row that are well-formed
+// numbers but still wrong for a payslip.
+func validateEmployee(emp *model.Employee, r *rowIssues) {
+	if emp.Month == "" || emp.Year == "" {
+		r.add("Month", strings.TrimSpace(emp.Month+" "+emp.Year), "missing pay month (add Month and Year columns or pass -month and -year)")
+	} else if _, ok := emp.PeriodStart(); !ok {
+		r.add("Month", strings.TrimSpace(emp.Month+" "+emp.Year), "unknown pay month (expected e.g. May 2024)")
+	}
+	if emp.Email == "" {
+		r.add("Email", "", "missing email")
+	} else if _, err := mail.ParseAddress(emp.Email); err != nil {
+		r.add("Email", emp.Email, "malformed email")
+	}
+	if emp.PAN != "" && !panPattern.MatchString(strings.ToUpper(strings.TrimSpace(emp.PAN))) {
+		r.add("PAN", emp.PAN, "malformed PAN (expected AAAAA9999A)")
+	}
+	if emp.UAN != "" && !uanPattern.MatchString(strings.TrimSpace(emp.UAN)) {
+		r.add("UAN", emp.UAN, "malformed UAN (expected 12 digits)")
+	}
+	if emp.ESINo != "" && !esiPattern.MatchString(strings.TrimSpace(emp.ESINo)) {
+		r.add("ESINo", emp.ESINo, "malformed ESI IP number (expected 10 digits)")
+	}
+	switch strings.ToLower(strings.TrimSpace(emp.TaxRegime)) {
+	case "", "old", "new":
+	default:
+		r.add("TaxRegime", emp.TaxRegime, "unknown tax regime (expected old or new)")
+	}
+	if emp.IFSC != "" && !ifscPattern.MatchString(strings.ToUpper(strings.TrimSpace(emp.IFSC))) {
+		r.add("IFSC", emp.IFSC, "malformed IFSC (expected AAAA0XXXXXX)")
+	}
+
+	for _, f := range fields {
+		if f.amount == nil {
+			continue
+		}
+		if v := *f.amount(emp); v < 0 {
+			r.add(f.name, v.String(), "negative amount")
+		}
+	}
+
+	for _, c := range slices.Concat(emp.ExtraEarnings, emp.ExtraDeductions) {
+		if c.Amount < 0 {
+			r.add(c.Name, c.Amount.String(), "negative amount")
+		}
+	}
+
+	for _, d := range []struct {
+		field string
+		days  float64
+	}{{"StandardDays", emp.StandardDays}, {"PayableDays", emp.PayableDays}, {"LOPDays", emp.LOPDays}} {
+		if d.days < 0 {
+			r.add(d.field, formatDays(d.days), "negative number of days")
+		}
+	}
+	if !emp.DOB.IsZero() && !emp.DOJ.IsZero() && !emp.DOB.Before(emp.DOJ) {
+		r.add("DOB", model.FormatDate(emp.DOB), "date of birth is not before date of joining")
+	}
+	if !emp.DOJ.IsZero() && !emp.ExitDate.IsZero() && emp.ExitDate.Before(emp.DOJ) {
+		r.add("ExitDate", model.FormatDate(emp.ExitDate), "exit date is before date of joining")
+	}
+	if start, ok := emp.PeriodStart(); ok {
+		end := start.AddDate(0, 1, -1)
+		if emp.DOJ.After(end) {
+			r.add("DOJ", model.FormatDate(emp.DOJ), "joined after "+emp.Month+" "+emp.Year)
+		}
+		if !emp.ExitDate.IsZero() && emp.ExitDate.Before(start) {
+			r.add("ExitDate", model.FormatDate(emp.ExitDate), "left before "+emp.Month+" "+emp.Year)
+		}
+	}
+	if monthDays := model.DaysInMonth(emp.Month, emp.Year); emp.LOPDays > float64(monthDays) {
+		r.add("LOPDays", formatDays(emp.LOPDays), "loss of pay days exceed days in "+emp.Month+" "+emp.Year)
+	}
+}
+
+func formatDays(d float64) string {
+	return strconv.FormatFloat(d, 'f', -1, 64)
+}
+
+// employeeKey identifies an employee within one pay month for duplicate detection.
+func employeeKey(emp *model.Employee) string {
+	id := strings.ToLower(strings.TrimSpace(emp.Email))
+	if id == "" {
+		id = strings.ToLower(strings.TrimSpace(emp.Name))
+	}
+	return id + "|" + strings.ToLower(emp.Month) + "|" + emp.Year
+}
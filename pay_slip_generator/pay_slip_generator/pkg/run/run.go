@@ -0,0 +1,304 @@
+// Package run is the pay run shared by the binaries: the flags that
+// configure the input, the payroll engine, payslip generation, pay history
+// and the ledger, and the steps that compute and record every payslip. Each
+// binary adds its own flags and steps (e.g. sending) around it.
+package run
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"pay_slip_generator/pkg/company"
+	"pay_slip_generator/pkg/generator"
+	"pay_slip_generator/pkg/history"
+	"pay_slip_generator/pkg/ledger"
+	"pay_slip_generator/pkg/model"
+	"pay_slip_generator/pkg/money"
+	"pay_slip_generator/pkg/payroll"
+	"pay_slip_generator/pkg/reader"
+	"runtime"
+	"strings"
+)
+
+// Config holds the shared flags.
+type Config struct {
+	Month        string
+	Year         string
+	Mapping      string
+	Strict       bool
+	Rounding     string
+	Proration    string
+	Holidays     string
+	Statutory    string
+	PTSlabs      string
+	TaxData      string
+	Declarations string
+	Structures   string
+	History      string
+	Ledger       string
+	Operator     string
+	Workers      int
+	PDFPassword  string
+	Layout       string
+	Fonts        string
+	Translations string
+	Company      string
+}
+
+// RegisterFlags defines the shared flags on fs and returns the Config they
+// fill in when fs is parsed.
+func RegisterFlags(fs *flag.FlagSet) *Config {
+	c := new(Config)
+	fs.StringVar(&c.Month, "month", "", "Pay month (e.g. May) for rows without a Month column or value")
+	fs.StringVar(&c.Year, "year", "", "Pay year (e.g. 2024) for rows without a Year column or value")
+	fs.StringVar(&c.Mapping, "mapping", "", "Path to a YAML/JSON column mapping file (default: built-in aliases)")
+	fs.BoolVar(&c.Strict, "strict", false, "Abort before generating anything if the input has validation issues")
+	fs.StringVar(&c.Rounding, "rounding", "paisa", "Round amounts half up to the nearest paisa or rupee")
+	fs.StringVar(&c.Proration, "proration", "calendar", "Proration basis for loss of pay: calendar, 30day or working")
+	fs.StringVar(&c.Holidays, "holidays", "", "Path to a YAML/JSON holiday calendar (for -proration working)")
+	fs.StringVar(&c.Statutory, "statutory", "", "Comma-separated statutory computations to run instead of trusting the sheet: pf, esi, pt, tds")
+	fs.StringVar(&c.PTSlabs, "pt-slabs", "", "Path to a YAML/JSON file overriding the built-in professional tax slabs (enables pt)")
+	fs.StringVar(&c.TaxData, "tax-data", "", "Directory of per-FY YAML/JSON income tax rate files overriding the built-in ones (enables tds)")
+	fs.StringVar(&c.Declarations, "declarations", "", "Path to a YAML/JSON file of employee tax declarations (regime, 80C/80D, rent for HRA exemption)")
+	fs.StringVar(&c.Structures, "structures", "", "Path to a YAML/JSON salary structures file (derive pay from annual CTC)")
+	fs.StringVar(&c.History, "history", "", "Path to the pay history file used for year-to-date totals, e.g. output/history.json (default: no history)")
+	fs.StringVar(&c.Ledger, "ledger", "", "Path to the SQLite payroll ledger recording runs, payslips and deliveries (also the source of YTD totals)")
+	fs.StringVar(&c.Operator, "operator", os.Getenv("USER"), "Operator name recorded in the ledger")
+	fs.IntVar(&c.Workers, "workers", runtime.NumCPU(), "Number of payslips generated in parallel")
+	fs.StringVar(&c.PDFPassword, "pdf-password", "", "Encrypt payslips with a per-employee password pattern, e.g. {PAN:4}{DOB:DDMM} (owner password secret from PDF_OWNER_SECRET)")
+	fs.StringVar(&c.Layout, "layout", "default", fmt.Sprintf("Payslip layout: built-in %s, or a YAML/JSON layout file", strings.Join(generator.LayoutNames(), " or ")))
+	fs.StringVar(&c.Fonts, "fonts", "", "Directory of NotoSans*-Regular/Bold.ttf fonts added to the built-in ones (Unicode names, rupee sign)")
+	fs.StringVar(&c.Translations, "translations", "", "Path to a YAML/JSON translations file; payslip labels are printed bilingually")
+	fs.StringVar(&c.Company, "company", "", "Path to a YAML/JSON company profiles file (letterhead, logo, colours, footer per legal entity)")
+	return c
+}
+
+// Session is a pay run configured from a Config.
+type Session struct {
+	Config    *Config
+	Reader    reader.Options
+	Engine    *payroll.Engine
+	Generator generator.Options
+	History   *history.Store // nil without -history
+	Ledger    *ledger.Ledger // nil without -ledger
+	run       *ledger.Run    // nil unless Start recorded the run
+}
+
+// Open loads every file the config names. Close the session when done.
+func (c *Config) Open() (*Session, error) {
+	s := &Session{Config: c}
+	if err := s.loadEngine(); err != nil {
+		return nil, err
+	}
+	if err := s.loadGenerator(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if c.History != "" {
+		if s.History, err = history.Open(c.History); err != nil {
+			return nil, fmt.Errorf("open pay history: %w", err)
+		}
+	}
+	if c.Ledger != "" {
+		if s.Ledger, err = ledger.Open(c.Ledger); err != nil {
+			return nil, fmt.Errorf("open ledger: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *Session) loadEngine() error {
+	c := s.Config
+	s.Reader.Month, s.Reader.Year = strings.TrimSpace(c.Month), strings.TrimSpace(c.Year)
+	var err error
+	if c.Mapping != "" {
+		if s.Reader.Mapping, err = reader.LoadMapping(c.Mapping); err != nil {
+			return fmt.Errorf("load column mapping: %w", err)
+		}
+	}
+	rounding, err := money.ParseRounding(c.Rounding)
+	if err != nil {
+		return fmt.Errorf("invalid -rounding: %w", err)
+	}
+	e := &payroll.Engine{Rounding: rounding}
+	if e.Proration.Basis, err = payroll.ParseBasis(c.Proration); err != nil {
+		return fmt.Errorf("invalid -proration: %w", err)
+	}
+	if c.Holidays != "" {
+		if e.Proration.Calendar, err = payroll.LoadCalendar(c.Holidays); err != nil {
+			return fmt.Errorf("load holiday calendar: %w", err)
+		}
+	}
+	if err := e.EnableStatutory(c.Statutory); err != nil {
+		return fmt.Errorf("invalid -statutory: %w", err)
+	}
+	if c.PTSlabs != "" {
+		if e.PT, err = payroll.LoadPT(c.PTSlabs); err != nil {
+			return fmt.Errorf("load professional tax slabs: %w", err)
+		}
+	}
+	if c.TaxData != "" {
+		if e.Tax, err = payroll.LoadTax(c.TaxData); err != nil {
+			return fmt.Errorf("load income tax rates: %w", err)
+		}
+	}
+	if c.Declarations != "" {
+		if e.Declarations, err = payroll.LoadDeclarations(c.Declarations); err != nil {
+			return fmt.Errorf("load tax declarations: %w", err)
+		}
+	}
+	if c.Structures != "" {
+		if e.Structures, err = payroll.LoadStructures(c.Structures); err != nil {
+			return fmt.Errorf("load salary structures: %w", err)
+		}
+	}
+	s.Engine = e
+	return nil
+}
+
+func (s *Session) loadGenerator() error {
+	c, opts := s.Config, &s.Generator
+	var err error
+	opts.OwnerSecret = os.Getenv("PDF_OWNER_SECRET")
+	if c.PDFPassword != "" {
+		if opts.Password, err = generator.ParsePasswordPattern(c.PDFPassword); err != nil {
+			return fmt.Errorf("invalid -pdf-password: %w", err)
+		}
+		if opts.OwnerSecret == "" {
+			return fmt.Errorf("-pdf-password needs PDF_OWNER_SECRET set to a secret for the owner password")
+		}
+	}
+	if opts.Layout, err = generator.LoadLayout(c.Layout); err != nil {
+		return fmt.Errorf("invalid -layout: %w", err)
+	}
+	if opts.Fonts, err = generator.LoadFonts(c.Fonts); err != nil {
+		return fmt.Errorf("load fonts: %w", err)
+	}
+	if !opts.Fonts.Unicode() {
+		log.Printf("No NotoSans-Regular.ttf fonts (see pkg/generator/fonts/README.md): payslips use the core font and print \"?\" for \"₹\" and Indian scripts")
+	}
+	if c.Translations != "" {
+		if opts.Translations, err = generator.LoadTranslations(c.Translations); err != nil {
+			return fmt.Errorf("load translations: %w", err)
+		}
+		if err := opts.Translations.CheckFonts(opts.Fonts); err != nil {
+			return fmt.Errorf("invalid -translations: %w", err)
+		}
+	}
+	if c.Company != "" {
+		if opts.Companies, err = company.Load(c.Company); err != nil {
+			return fmt.Errorf("load company profiles: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the ledger, if there is one.
+func (s *Session) Close() error {
+	if s.Ledger == nil {
+		return nil
+	}
+	return s.Ledger.Close()
+}
+
+// CheckIssues logs input validation issues. In strict mode it also writes
+// them to report (.json and .csv) and fails the run.
+func (s *Session) CheckIssues(issues []reader.Issue, report string) error {
+	for _, issue := range issues {
+		log.Printf("  [WARN] %s", issue)
+	}
+	if !s.Config.Strict || len(issues) == 0 {
+		return nil
+	}
+	if err := reader.WriteReport(report, issues); err != nil {
+		return fmt.Errorf("write validation report: %w", err)
+	}
+	return fmt.Errorf("strict mode: %d validation issue(s), see %s.json / %s.csv", len(issues), report, report)
+}
+
+// Start records the start of this run over inputFile in the ledger, if
+// there is one. Payslips are only recorded in the ledger after Start.
+func (s *Session) Start(inputFile string, employees []model.Employee) error {
+	if s.Ledger == nil {
+		return nil
+	}
+	var month, year string
+	if len(employees) > 0 {
+		month, year = employees[0].Month, employees[0].Year
+	}
+	run, err := s.Ledger.StartRun(inputFile, month, year, s.Config.Operator)
+	if err != nil {
+		return fmt.Errorf("record run in ledger: %w", err)
+	}
+	s.run = run
+	return nil
+}
+
+// Compute sets emp's year-to-date totals from the ledger or, without one,
+// the history store, then computes its pay, logging every mismatch with the
+// sheet.
+func (s *Session) Compute(emp *model.Employee) error {
+	switch {
+	case s.Ledger != nil:
+		if err := s.Ledger.Apply(emp); err != nil {
+			log.Printf("  [WARN] %s: YTD from ledger: %v", emp.Name, err)
+		}
+	case s.History != nil:
+		s.History.Apply(emp)
+	}
+	mismatches, err := s.Engine.Compute(emp)
+	if err != nil {
+		return err
+	}
+	for _, m := range mismatches {
+		log.Printf("  [WARN] %s (%s %s): %s", emp.Name, emp.Month, emp.Year, m)
+	}
+	return nil
+}
+
+// Record adds emp's generated payslip at pdfPath to the history store and,
+// when the run is recorded, the ledger. It returns the ledger's payslip id,
+// 0 when there is none.
+func (s *Session) Record(emp *model.Employee, pdfPath string) int64 {
+	if s.History != nil {
+		if err := s.History.Record(emp); err != nil {
+			log.Printf("  [WARN] %s: %v", emp.Name, err)
+		}
+	}
+	if s.run == nil {
+		return 0
+	}
+	id, err := s.Ledger.RecordPayslip(s.run, emp, pdfPath)
+	if err != nil {
+		log.Printf("  [WARN] %v", err)
+	}
+	return id
+}
+
+// RecordDelivery stores a delivery attempt of the payslip with the ledger
+// id payslipID, if it was recorded. detail is why it failed or was skipped.
+func (s *Session) RecordDelivery(payslipID int64, status, recipient, detail string) {
+	if s.Ledger == nil || payslipID == 0 {
+		return
+	}
+	if err := s.Ledger.RecordDelivery(payslipID, status, recipient, detail); err != nil {
+		log.Printf("  [WARN] Failed to record delivery to %s: %v", recipient, err)
+	}
+}
+
+// Finish saves the history store and records the end of the run in the
+// ledger.
+func (s *Session) Finish() {
+	if s.History != nil {
+		if err := s.History.Save(); err != nil {
+			log.Printf("Failed to save pay history: %v", err)
+		}
+	}
+	if s.run != nil {
+		if err := s.Ledger.FinishRun(s.run); err != nil {
+			log.Printf("Failed to record end of run in ledger: %v", err)
+		}
+	}
+}